@@ -0,0 +1,46 @@
+package curve25519
+
+import "errors"
+
+const (
+	ageRecipientHRP = "age"
+	ageIdentityHRP  = "AGE-SECRET-KEY-"
+)
+
+// AgeRecipient returns pk as an age X25519 recipient, age1 followed by
+// lowercase Bech32.
+func (pk *PublicKey) AgeRecipient() string {
+	return bech32Encode(ageRecipientHRP, pk[:])
+}
+
+// ParseAgeRecipient parses an age X25519 recipient.
+func ParseAgeRecipient(s string) (pk *PublicKey, err error) {
+	hrp, data, err := bech32Decode(s)
+	if err != nil {
+		return nil, err
+	}
+	if hrp != ageRecipientHRP {
+		return nil, errors.New("curve25519: not an age X25519 recipient")
+	}
+	return ParsePublicKey(data)
+}
+
+// AgeIdentity returns sk as an age X25519 identity, AGE-SECRET-KEY-1
+// followed by uppercase Bech32.
+func (sk *PrivateKey) AgeIdentity() string {
+	return bech32Encode(ageIdentityHRP, sk.raw[:])
+}
+
+// ParseAgeIdentity parses an age X25519 identity.  age doesn't clamp the keys
+// it generates, so AgeIdentity gives another string for most of them, of the
+// same key.
+func ParseAgeIdentity(s string) (sk *PrivateKey, err error) {
+	hrp, data, err := bech32Decode(s)
+	if err != nil {
+		return nil, err
+	}
+	if hrp != ageIdentityHRP {
+		return nil, errors.New("curve25519: not an age X25519 identity")
+	}
+	return ParsePrivateKey(data)
+}
@@ -0,0 +1,174 @@
+// Package age implements the age file encryption format
+// (age-encryption.org/v1), with the X25519 recipients and identities of this
+// module.  Files it encrypts decrypt with the age command line tool, and the
+// other way around.
+//
+// A file is a header, where each Recipient wraps a random file key into
+// stanzas, followed by the payload encrypted under the file key.  Decrypt
+// tries each Identity on the stanzas until one unwraps the file key, checks
+// the header MAC, and then streams the payload, only ever releasing
+// authenticated plaintext.
+package age
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	fileKeySize     = 16
+	streamNonceSize = 16
+)
+
+var (
+	// ErrIncorrectIdentity is returned by Identity.Unwrap when none of the
+	// stanzas are for the identity.
+	ErrIncorrectIdentity = errors.New("age: incorrect identity for recipient stanza")
+	// ErrNoMatch is returned by Decrypt when none of the identities unwrap
+	// the file key.
+	ErrNoMatch = errors.New("age: no identity matched any of the recipients")
+	// ErrInvalidHeader is wrapped by the errors of malformed headers.
+	ErrInvalidHeader = errors.New("age: invalid header")
+	// ErrHeaderMAC is returned by Decrypt when the header MAC doesn't verify.
+	ErrHeaderMAC = errors.New("age: bad header MAC")
+	// ErrInvalidPayload is wrapped by the errors of payloads that are
+	// truncated, tampered with, or followed by trailing data.
+	ErrInvalidPayload = errors.New("age: invalid payload")
+)
+
+// Stanza is a recipient stanza of a header: a type, its arguments, and a
+// body, usually a wrapped file key.
+type Stanza struct {
+	Type string
+	Args []string
+	Body []byte
+}
+
+// Recipient wraps file keys into stanzas.
+type Recipient interface {
+	Wrap(reader io.Reader, fileKey []byte) ([]*Stanza, error)
+}
+
+// Identity unwraps file keys from stanzas.  It returns ErrIncorrectIdentity
+// when none of the stanzas are for it, which Decrypt skips, and another error
+// when one of its stanzas is malformed, which Decrypt doesn't.
+type Identity interface {
+	Unwrap(stanzas []*Stanza) (fileKey []byte, err error)
+}
+
+// Encrypt writes the header of a file to the recipients to dst, and returns a
+// writer that encrypts the payload to dst.  The payload is incomplete until
+// the writer is closed, which doesn't close dst.  reader is the source of the
+// file key, of the payload nonce and of the recipients' randomness.
+func Encrypt(reader io.Reader, dst io.Writer, recipients ...Recipient) (io.WriteCloser, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("age: no recipients")
+	}
+
+	fileKey := make([]byte, fileKeySize)
+	if _, err := io.ReadFull(reader, fileKey); err != nil {
+		return nil, err
+	}
+	h := &header{}
+	for _, r := range recipients {
+		stanzas, err := r.Wrap(reader, fileKey)
+		if err != nil {
+			return nil, err
+		}
+		h.stanzas = append(h.stanzas, stanzas...)
+	}
+	mac, err := headerMAC(fileKey, h)
+	if err != nil {
+		return nil, err
+	}
+	h.mac = mac
+	if err := h.marshal(dst); err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, streamNonceSize)
+	if _, err := io.ReadFull(reader, nonce); err != nil {
+		return nil, err
+	}
+	if _, err := dst.Write(nonce); err != nil {
+		return nil, err
+	}
+	return newStreamWriter(streamKey(fileKey, nonce), dst)
+}
+
+// Decrypt reads the header of a file from src, unwraps its file key with the
+// first of identities that matches, and returns a reader of the payload.
+// Errors of the payload come from the reader, after the plaintext before
+// them.
+func Decrypt(src io.Reader, identities ...Identity) (io.Reader, error) {
+	if len(identities) == 0 {
+		return nil, errors.New("age: no identities")
+	}
+
+	h, payload, err := parseHeader(src)
+	if err != nil {
+		return nil, err
+	}
+	fileKey, err := unwrap(h.stanzas, identities)
+	if err != nil {
+		return nil, err
+	}
+	mac, err := headerMAC(fileKey, h)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(mac, h.mac) {
+		return nil, ErrHeaderMAC
+	}
+
+	nonce := make([]byte, streamNonceSize)
+	if _, err := io.ReadFull(payload, nonce); err == io.EOF || err == io.ErrUnexpectedEOF {
+		return nil, headerError("missing payload nonce")
+	} else if err != nil {
+		return nil, err
+	}
+	return newStreamReader(streamKey(fileKey, nonce), payload)
+}
+
+func unwrap(stanzas []*Stanza, identities []Identity) ([]byte, error) {
+	for _, id := range identities {
+		fileKey, err := id.Unwrap(stanzas)
+		if errors.Is(err, ErrIncorrectIdentity) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if len(fileKey) != fileKeySize {
+			return nil, errors.New("age: identity returned a file key of invalid length")
+		}
+		return fileKey, nil
+	}
+	return nil, ErrNoMatch
+}
+
+/* HMAC-SHA256 of the header up to the MAC, under a key derived from the
+ * file key */
+func headerMAC(fileKey []byte, h *header) ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, fileKey, nil, []byte("header")), key); err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, key)
+	if err := h.marshalWithoutMAC(mac); err != nil {
+		return nil, err
+	}
+	return mac.Sum(nil), nil
+}
+
+/* the payload key, derived from the file key and the payload nonce */
+func streamKey(fileKey, nonce []byte) []byte {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, fileKey, nonce, []byte("payload")), key); err != nil {
+		panic(err)
+	}
+	return key
+}
@@ -0,0 +1,225 @@
+package age
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	curve25519 "github.com/moonfruit/go-curve25519"
+	"github.com/stretchr/testify/require"
+)
+
+var reader = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+/* a vector of the age test kit (c2sp.org/CCTV/age), those with X25519
+ * identities only */
+type testkitVector struct {
+	expect     string
+	payload    []byte
+	identities []Identity
+	file       []byte
+}
+
+func readTestkitVector(t *testing.T, path string) *testkitVector {
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+
+	v := &testkitVector{}
+	r := bufio.NewReader(bytes.NewReader(data))
+	compressed := false
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			break
+		}
+		key, value := line, ""
+		if i := strings.Index(line, ": "); i >= 0 {
+			key, value = line[:i], line[i+2:]
+		}
+		switch key {
+		case "expect":
+			v.expect = value
+		case "payload":
+			v.payload, err = hex.DecodeString(value)
+			require.NoError(t, err)
+		case "identity":
+			identity, err := ParseX25519Identity(value)
+			require.NoError(t, err)
+			v.identities = append(v.identities, identity)
+		case "compressed":
+			require.Equal(t, "zlib", value)
+			compressed = true
+		}
+	}
+
+	if compressed {
+		zr, err := zlib.NewReader(r)
+		require.NoError(t, err)
+		v.file, err = ioutil.ReadAll(zr)
+		require.NoError(t, err)
+	} else {
+		v.file, err = ioutil.ReadAll(r)
+		require.NoError(t, err)
+	}
+	return v
+}
+
+func TestTestkit(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "testkit", "*"))
+	require.NoError(t, err)
+	require.NotEmpty(t, paths)
+
+	for _, path := range paths {
+		v := readTestkitVector(t, path)
+		name := filepath.Base(path)
+
+		var plaintext []byte
+		payload, err := Decrypt(bytes.NewReader(v.file), v.identities...)
+		if err == nil {
+			plaintext, err = ioutil.ReadAll(payload)
+		}
+		switch v.expect {
+		case "success":
+			require.NoError(t, err, name)
+		case "no match":
+			require.Equal(t, ErrNoMatch, err, name)
+		case "HMAC failure":
+			require.Equal(t, ErrHeaderMAC, err, name)
+		case "header failure":
+			require.True(t, errors.Is(err, ErrInvalidHeader), "%s: %v", name, err)
+		case "payload failure":
+			require.True(t, errors.Is(err, ErrInvalidPayload), "%s: %v", name, err)
+		default:
+			t.Fatalf("%s: unknown expect %q", name, v.expect)
+		}
+		if v.payload != nil {
+			// even a payload failure releases the plaintext before it
+			digest := sha256.Sum256(plaintext)
+			require.Equal(t, v.payload, digest[:], name)
+		}
+	}
+}
+
+func encrypt(t *testing.T, plaintext []byte, recipients ...Recipient) []byte {
+	var buf bytes.Buffer
+	w, err := Encrypt(reader, &buf, recipients...)
+	require.NoError(t, err)
+	// in uneven writes, to cross chunk boundaries
+	for p := plaintext; len(p) > 0; {
+		n := reader.Intn(3*chunkSize/2) + 1
+		if n > len(p) {
+			n = len(p)
+		}
+		_, err = w.Write(p[:n])
+		require.NoError(t, err)
+		p = p[n:]
+	}
+	require.NoError(t, w.Close())
+	_, err = w.Write([]byte{0})
+	require.Error(t, err)
+	return buf.Bytes()
+}
+
+func decrypt(file []byte, identities ...Identity) ([]byte, error) {
+	payload, err := Decrypt(bytes.NewReader(file), identities...)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(payload)
+}
+
+func TestEncrypt(t *testing.T) {
+	identity := NewX25519Identity(curve25519.GenerateKeyFrom(reader))
+	for _, size := range []int{0, 1, chunkSize - 1, chunkSize, chunkSize + 1, 2 * chunkSize, 3*chunkSize + 100} {
+		plaintext := make([]byte, size)
+		reader.Read(plaintext)
+		file := encrypt(t, plaintext, identity.Recipient())
+		decrypted, err := decrypt(file, identity)
+		require.NoError(t, err, size)
+		require.Equal(t, plaintext, decrypted, size)
+
+		// a truncated payload never decrypts, even at a chunk boundary
+		for _, n := range []int{1, 16, chunkSize + 16} {
+			if n > size+16 {
+				continue
+			}
+			_, err = decrypt(file[:len(file)-n], identity)
+			require.Error(t, err, size)
+		}
+	}
+}
+
+func TestRecipients(t *testing.T) {
+	alice := NewX25519Identity(curve25519.GenerateKeyFrom(reader))
+	bob := NewX25519Identity(curve25519.GenerateKeyFrom(reader))
+	eve := NewX25519Identity(curve25519.GenerateKeyFrom(reader))
+	plaintext := []byte("attack at dawn")
+
+	file := encrypt(t, plaintext, alice.Recipient(), bob.Recipient())
+	for _, identities := range [][]Identity{{alice}, {bob}, {eve, bob}} {
+		decrypted, err := decrypt(file, identities...)
+		require.NoError(t, err)
+		require.Equal(t, plaintext, decrypted)
+	}
+	_, err := decrypt(file, eve)
+	require.Equal(t, ErrNoMatch, err)
+	_, err = decrypt(file)
+	require.Error(t, err)
+	_, err = Encrypt(reader, ioutil.Discard)
+	require.Error(t, err)
+
+	// the header MAC covers the stanzas
+	h, payload, err := parseHeader(bytes.NewReader(file))
+	require.NoError(t, err)
+	rest, err := ioutil.ReadAll(payload)
+	require.NoError(t, err)
+	h.stanzas = h.stanzas[1:]
+	var buf bytes.Buffer
+	require.NoError(t, h.marshal(&buf))
+	_, err = decrypt(append(buf.Bytes(), rest...), bob)
+	require.Equal(t, ErrHeaderMAC, err)
+
+	// and the payload its chunks
+	file[len(file)-1] ^= 1
+	_, err = decrypt(file, alice)
+	require.True(t, errors.Is(err, ErrInvalidPayload))
+}
+
+func TestX25519Keys(t *testing.T) {
+	// as given by age-keygen, whose identities aren't clamped
+	identity, err := ParseX25519Identity("AGE-SECRET-KEY-1EGTZVFFV20835NWYV6270LXYVK2VKNX2MMDKWYKLMGR48UAWX40Q2P2LM0")
+	require.NoError(t, err)
+	require.Equal(t, "age1xmwwc06ly3ee5rytxm9mflaz2u56jjj36s0mypdrwsvlul66mv4q47ryef", identity.Recipient().String())
+
+	recipient, err := ParseX25519Recipient(identity.Recipient().String())
+	require.NoError(t, err)
+	require.Equal(t, identity.Recipient(), recipient)
+	require.Equal(t, identity.Recipient().PublicKey(), recipient.PublicKey())
+
+	_, err = ParseX25519Recipient("AGE-SECRET-KEY-1EGTZVFFV20835NWYV6270LXYVK2VKNX2MMDKWYKLMGR48UAWX40Q2P2LM0")
+	require.Error(t, err)
+	_, err = ParseX25519Identity("age1xmwwc06ly3ee5rytxm9mflaz2u56jjj36s0mypdrwsvlul66mv4q47ryef")
+	require.Error(t, err)
+}
+
+func TestAgeCLI(t *testing.T) {
+	identity, err := ParseX25519Identity("AGE-SECRET-KEY-1EGTZVFFV20835NWYV6270LXYVK2VKNX2MMDKWYKLMGR48UAWX40Q2P2LM0")
+	require.NoError(t, err)
+	// two full chunks, as encrypted by the age command line tool
+	file, err := ioutil.ReadFile(filepath.Join("testdata", "cli.age"))
+	require.NoError(t, err)
+	plaintext, err := decrypt(file, identity)
+	require.NoError(t, err)
+	require.Equal(t, bytes.Repeat([]byte("0123456789abcdef"), chunkSize/8), plaintext)
+}
@@ -0,0 +1,210 @@
+package age
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	intro = "age-encryption.org/v1\n"
+
+	/* stanza bodies are wrapped at 64 columns, and end with a shorter line */
+	columnsPerLine = 64
+	bytesPerLine   = columnsPerLine / 4 * 3
+
+	maxHeaderSize = 2 << 20
+)
+
+var (
+	stanzaPrefix = []byte("->")
+	footerPrefix = []byte("---")
+	b64          = base64.RawStdEncoding.Strict()
+)
+
+/* the header of a file: the recipient stanzas and their MAC */
+type header struct {
+	stanzas []*Stanza
+	mac     []byte
+}
+
+func headerError(format string, a ...interface{}) error {
+	return fmt.Errorf("%w: "+format, append([]interface{}{ErrInvalidHeader}, a...)...)
+}
+
+/* strict base64, which unlike encoding/base64 doesn't skip newlines */
+func decodeBase64(s string) ([]byte, error) {
+	if strings.ContainsAny(s, "\r\n") {
+		return nil, headerError("unexpected newline in base64")
+	}
+	return b64.DecodeString(s)
+}
+
+/* arguments and types are non-empty strings of printable ASCII */
+func isValidString(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < 33 || s[i] > 126 {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Stanza) marshal(w io.Writer) error {
+	if !isValidString(s.Type) {
+		return fmt.Errorf("age: invalid stanza type %q", s.Type)
+	}
+	line := "-> " + s.Type
+	for _, arg := range s.Args {
+		if !isValidString(arg) {
+			return fmt.Errorf("age: invalid stanza argument %q", arg)
+		}
+		line += " " + arg
+	}
+	body := b64.EncodeToString(s.Body)
+	for ; len(body) >= columnsPerLine; body = body[columnsPerLine:] {
+		line += "\n" + body[:columnsPerLine]
+	}
+	_, err := io.WriteString(w, line+"\n"+body+"\n")
+	return err
+}
+
+/* the header up to the MAC, which the MAC covers */
+func (h *header) marshalWithoutMAC(w io.Writer) error {
+	if _, err := io.WriteString(w, intro); err != nil {
+		return err
+	}
+	for _, s := range h.stanzas {
+		if err := s.marshal(w); err != nil {
+			return err
+		}
+	}
+	_, err := w.Write(footerPrefix)
+	return err
+}
+
+func (h *header) marshal(w io.Writer) error {
+	if err := h.marshalWithoutMAC(w); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, " "+b64.EncodeToString(h.mac)+"\n")
+	return err
+}
+
+/* reads the lines of a header, up to maxHeaderSize */
+type headerReader struct {
+	r *bufio.Reader
+	n int
+}
+
+func (r *headerReader) readLine() ([]byte, error) {
+	var line []byte
+	for {
+		fragment, err := r.r.ReadSlice('\n')
+		if r.n += len(fragment); r.n > maxHeaderSize {
+			return nil, headerError("header is too large")
+		}
+		line = append(line, fragment...)
+		switch err {
+		case nil:
+			return line, nil
+		case bufio.ErrBufferFull:
+			continue
+		case io.EOF:
+			return nil, headerError("unexpected end of header")
+		default:
+			return nil, err
+		}
+	}
+}
+
+/* the prefix of a line and its arguments, or none if any is invalid */
+func splitArgs(line []byte) (prefix string, args []string) {
+	fields := strings.Split(strings.TrimSuffix(string(line), "\n"), " ")
+	for _, arg := range fields[1:] {
+		if !isValidString(arg) {
+			return fields[0], nil
+		}
+	}
+	return fields[0], fields[1:]
+}
+
+func (r *headerReader) readStanza() (*Stanza, error) {
+	line, err := r.readLine()
+	if err != nil {
+		return nil, err
+	}
+	prefix, args := splitArgs(line)
+	if prefix != string(stanzaPrefix) || len(args) < 1 {
+		return nil, headerError("malformed stanza line %q", line)
+	}
+	s := &Stanza{Type: args[0], Args: args[1:], Body: []byte{}}
+	for {
+		if line, err = r.readLine(); err != nil {
+			return nil, err
+		}
+		b, err := decodeBase64(strings.TrimSuffix(string(line), "\n"))
+		if err != nil {
+			return nil, headerError("malformed stanza body line %q", line)
+		}
+		if len(b) > bytesPerLine {
+			return nil, headerError("stanza body line %q is too long", line)
+		}
+		s.Body = append(s.Body, b...)
+		if len(b) < bytesPerLine {
+			return s, nil
+		}
+	}
+}
+
+/* parses the header of src, and returns the rest of src */
+func parseHeader(src io.Reader) (*header, io.Reader, error) {
+	r := &headerReader{r: bufio.NewReader(src)}
+	line, err := r.readLine()
+	if err != nil {
+		return nil, nil, err
+	}
+	if string(line) != intro {
+		return nil, nil, headerError("unsupported version or not an age file")
+	}
+
+	h := &header{}
+	for {
+		peek, err := r.r.Peek(len(footerPrefix))
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, nil, headerError("unexpected end of header")
+		} else if err != nil {
+			return nil, nil, err
+		}
+		if !bytes.Equal(peek, footerPrefix) {
+			s, err := r.readStanza()
+			if err != nil {
+				return nil, nil, err
+			}
+			h.stanzas = append(h.stanzas, s)
+			continue
+		}
+
+		if line, err = r.readLine(); err != nil {
+			return nil, nil, err
+		}
+		prefix, args := splitArgs(line)
+		if prefix != string(footerPrefix) || len(args) != 1 {
+			return nil, nil, headerError("malformed MAC line %q", line)
+		}
+		if h.mac, err = decodeBase64(args[0]); err != nil || len(h.mac) != 32 {
+			return nil, nil, headerError("malformed MAC line %q", line)
+		}
+		break
+	}
+	if len(h.stanzas) == 0 {
+		return nil, nil, headerError("no recipient stanzas")
+	}
+	return h, r.r, nil
+}
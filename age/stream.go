@@ -0,0 +1,183 @@
+package age
+
+import (
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+/* STREAM: the payload is cut into 64 KiB chunks, each sealed under a nonce of
+ * an 11-byte big-endian counter and a flag byte that marks the last chunk.
+ * Only an empty payload has an empty last chunk */
+
+const (
+	chunkSize          = 64 * 1024
+	encryptedChunkSize = chunkSize + 16
+	lastChunkFlag      = 0x01
+)
+
+type streamNonce [chacha20poly1305.NonceSize]byte
+
+func (n *streamNonce) increment() {
+	for i := len(n) - 2; i >= 0; i-- {
+		n[i]++
+		if n[i] != 0 {
+			return
+		}
+	}
+	panic("age: chunk counter wrapped around")
+}
+
+func (n *streamNonce) setLast() {
+	n[len(n)-1] = lastChunkFlag
+}
+
+func (n *streamNonce) isZero() bool {
+	return *n == streamNonce{}
+}
+
+func payloadError(format string, a ...interface{}) error {
+	return fmt.Errorf("%w: "+format, append([]interface{}{ErrInvalidPayload}, a...)...)
+}
+
+type streamWriter struct {
+	aead  cipher.AEAD
+	dst   io.Writer
+	buf   []byte
+	nonce streamNonce
+	err   error
+}
+
+func newStreamWriter(key []byte, dst io.Writer) (*streamWriter, error) {
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, err
+	}
+	return &streamWriter{aead: aead, dst: dst, buf: make([]byte, 0, encryptedChunkSize)}, nil
+}
+
+func (w *streamWriter) Write(p []byte) (n int, err error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	for len(p) > 0 {
+		/* a full chunk is only flushed once more data follows, since the
+		 * last chunk may be full */
+		if len(w.buf) == chunkSize {
+			if w.err = w.flush(false); w.err != nil {
+				return n, w.err
+			}
+		}
+		m := copy(w.buf[len(w.buf):chunkSize], p)
+		w.buf = w.buf[:len(w.buf)+m]
+		p = p[m:]
+		n += m
+	}
+	return n, nil
+}
+
+// Close writes the last chunk.  It doesn't close the underlying writer.
+func (w *streamWriter) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.err = w.flush(true); w.err != nil {
+		return w.err
+	}
+	w.err = errors.New("age: write to a closed file")
+	return nil
+}
+
+func (w *streamWriter) flush(last bool) error {
+	if last {
+		w.nonce.setLast()
+	}
+	_, err := w.dst.Write(w.aead.Seal(w.buf[:0], w.nonce[:], w.buf, nil))
+	w.nonce.increment()
+	w.buf = w.buf[:0]
+	return err
+}
+
+type streamReader struct {
+	aead   cipher.AEAD
+	src    io.Reader
+	in     []byte
+	out    []byte
+	unread []byte
+	nonce  streamNonce
+	err    error
+}
+
+func newStreamReader(key []byte, src io.Reader) (*streamReader, error) {
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, err
+	}
+	return &streamReader{
+		aead: aead,
+		src:  src,
+		in:   make([]byte, encryptedChunkSize),
+		out:  make([]byte, 0, chunkSize),
+	}, nil
+}
+
+func (r *streamReader) Read(p []byte) (int, error) {
+	for len(r.unread) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		r.err = r.readChunk()
+	}
+	n := copy(p, r.unread)
+	r.unread = r.unread[n:]
+	return n, nil
+}
+
+/* decrypts the next chunk into unread, returning io.EOF after the last one */
+func (r *streamReader) readChunk() error {
+	in := r.in
+	n, err := io.ReadFull(r.src, in)
+	last := false
+	switch {
+	case err == io.EOF:
+		return payloadError("missing last chunk")
+	case err == io.ErrUnexpectedEOF:
+		if !r.nonce.isZero() && n == r.aead.Overhead() {
+			return payloadError("empty last chunk")
+		}
+		in, last = in[:n], true
+	case err != nil:
+		return err
+	}
+
+	nonce := r.nonce
+	if last {
+		nonce.setLast()
+	}
+	/* not in place, as a failed Open may clobber its output */
+	out, err := r.aead.Open(r.out, nonce[:], in, nil)
+	if err != nil && !last {
+		/* a full chunk may be the last one */
+		nonce.setLast()
+		out, err = r.aead.Open(r.out, nonce[:], in, nil)
+		last = true
+	}
+	if err != nil {
+		return payloadError("chunk authentication failed")
+	}
+	r.nonce.increment()
+	r.unread = out
+
+	if last {
+		if _, err := io.ReadFull(r.src, make([]byte, 1)); err == nil {
+			return payloadError("trailing data after the last chunk")
+		} else if err != io.EOF {
+			return err
+		}
+		return io.EOF
+	}
+	return nil
+}
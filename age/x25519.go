@@ -0,0 +1,154 @@
+package age
+
+import (
+	"crypto/sha256"
+	"io"
+
+	curve25519 "github.com/moonfruit/go-curve25519"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	x25519StanzaType = "X25519"
+	x25519Label      = "age-encryption.org/v1/X25519"
+)
+
+// X25519Recipient is the recipient of an X25519 public key, age1 followed by
+// Bech32.
+type X25519Recipient struct {
+	publicKey curve25519.PublicKey
+}
+
+// NewX25519Recipient returns the recipient of pk.
+func NewX25519Recipient(pk *curve25519.PublicKey) *X25519Recipient {
+	return &X25519Recipient{publicKey: *pk}
+}
+
+// ParseX25519Recipient parses a recipient as given by age-keygen.
+func ParseX25519Recipient(s string) (*X25519Recipient, error) {
+	pk, err := curve25519.ParseAgeRecipient(s)
+	if err != nil {
+		return nil, err
+	}
+	return NewX25519Recipient(pk), nil
+}
+
+// PublicKey returns the public key of r.
+func (r *X25519Recipient) PublicKey() *curve25519.PublicKey {
+	pk := r.publicKey
+	return &pk
+}
+
+// String returns r as age1 followed by Bech32.
+func (r *X25519Recipient) String() string {
+	return r.publicKey.AgeRecipient()
+}
+
+// Wrap wraps fileKey to an ephemeral key agreement with r.
+func (r *X25519Recipient) Wrap(reader io.Reader, fileKey []byte) ([]*Stanza, error) {
+	ephemeral, err := curve25519.GenerateKeyWithReader(reader)
+	if err != nil {
+		return nil, err
+	}
+	share := ephemeral.Public()
+	sharedSecret, err := ephemeral.SharedSecretChecked(&r.publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := chacha20poly1305.New(x25519WrappingKey(sharedSecret, share, &r.publicKey))
+	if err != nil {
+		return nil, err
+	}
+	return []*Stanza{{
+		Type: x25519StanzaType,
+		Args: []string{b64.EncodeToString(share[:])},
+		Body: aead.Seal(nil, make([]byte, aead.NonceSize()), fileKey, nil),
+	}}, nil
+}
+
+// X25519Identity is the identity of an X25519 private key, AGE-SECRET-KEY-1
+// followed by Bech32.
+type X25519Identity struct {
+	privateKey *curve25519.PrivateKey
+	publicKey  curve25519.PublicKey
+}
+
+// NewX25519Identity returns the identity of sk.
+func NewX25519Identity(sk *curve25519.PrivateKey) *X25519Identity {
+	return &X25519Identity{privateKey: sk, publicKey: *sk.Public()}
+}
+
+// ParseX25519Identity parses an identity as given by age-keygen.
+func ParseX25519Identity(s string) (*X25519Identity, error) {
+	sk, err := curve25519.ParseAgeIdentity(s)
+	if err != nil {
+		return nil, err
+	}
+	return NewX25519Identity(sk), nil
+}
+
+// Recipient returns the recipient of i.
+func (i *X25519Identity) Recipient() *X25519Recipient {
+	return NewX25519Recipient(&i.publicKey)
+}
+
+// Unwrap unwraps the file key from the X25519 stanza for i, skipping the
+// stanzas of other types.
+func (i *X25519Identity) Unwrap(stanzas []*Stanza) ([]byte, error) {
+	for _, s := range stanzas {
+		if s.Type != x25519StanzaType {
+			continue
+		}
+		fileKey, err := i.unwrap(s)
+		if err == ErrIncorrectIdentity {
+			continue
+		}
+		return fileKey, err
+	}
+	return nil, ErrIncorrectIdentity
+}
+
+func (i *X25519Identity) unwrap(s *Stanza) ([]byte, error) {
+	if len(s.Args) != 1 {
+		return nil, headerError("invalid X25519 stanza")
+	}
+	b, err := decodeBase64(s.Args[0])
+	if err != nil {
+		return nil, headerError("invalid X25519 ephemeral share")
+	}
+	share, err := curve25519.ParsePublicKey(b)
+	if err != nil {
+		return nil, headerError("invalid X25519 ephemeral share")
+	}
+	sharedSecret, err := i.privateKey.SharedSecretChecked(share)
+	if err != nil {
+		return nil, headerError("invalid X25519 ephemeral share: %v", err)
+	}
+	if len(s.Body) != fileKeySize+16 {
+		return nil, headerError("invalid X25519 stanza body")
+	}
+
+	aead, err := chacha20poly1305.New(x25519WrappingKey(sharedSecret, share, &i.publicKey))
+	if err != nil {
+		return nil, err
+	}
+	fileKey, err := aead.Open(nil, make([]byte, aead.NonceSize()), s.Body, nil)
+	if err != nil {
+		return nil, ErrIncorrectIdentity
+	}
+	return fileKey, nil
+}
+
+/* HKDF-SHA256 of the shared secret, salted with the ephemeral share and the
+ * recipient */
+func x25519WrappingKey(sharedSecret []byte, share, recipient *curve25519.PublicKey) []byte {
+	salt := make([]byte, 0, len(share)+len(recipient))
+	salt = append(append(salt, share[:]...), recipient[:]...)
+	key := make([]byte, chacha20poly1305.KeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, sharedSecret, salt, []byte(x25519Label)), key); err != nil {
+		panic(err)
+	}
+	return key
+}
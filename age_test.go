@@ -0,0 +1,83 @@
+package curve25519
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBech32(t *testing.T) {
+	// the valid and invalid checksums of BIP 173
+	for _, s := range []string{
+		"A12UEL5L",
+		"a12uel5l",
+		"an83characterlonghumanreadablepartthatcontainsthenumber1andtheexcludedcharactersbio1tt5tgs",
+		"abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw",
+		"split1checkupstagehandshakeupstreamerranterredcaperred2y9e3w",
+		"?1ezyfcl",
+	} {
+		hrp, data, err := bech32Decode(s)
+		require.NoError(t, err, s)
+		require.Equal(t, s, bech32Encode(hrp, data))
+	}
+	for _, s := range []string{
+		"\x201nwldj5",
+		"\x7f1axkwrx",
+		"pzry9x0s0muk",
+		"1pzry9x0s0muk",
+		"x1b4n0q5v",
+		"li1dgmt3",
+		"de1lg7wt\xff",
+		"A1G7SGD8",
+		"10a06t8",
+		"1qzzfhee",
+		"A12uEL5L",
+	} {
+		_, _, err := bech32Decode(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestAgeKeys(t *testing.T) {
+	// the identity of the age test vectors, and its recipient from age-keygen
+	privateKey, err := ParseAgeIdentity("AGE-SECRET-KEY-1EGTZVFFV20835NWYV6270LXYVK2VKNX2MMDKWYKLMGR48UAWX40Q2P2LM0")
+	require.NoError(t, err)
+	// which isn't clamped
+	require.Equal(t, "AGE-SECRET-KEY-1EQTZVFFV20835NWYV6270LXYVK2VKNX2MMDKWYKLMGR48UAWX40QXDR6XC", privateKey.AgeIdentity())
+	require.Equal(t, "age1xmwwc06ly3ee5rytxm9mflaz2u56jjj36s0mypdrwsvlul66mv4q47ryef", privateKey.Public().AgeRecipient())
+
+	publicKey, err := ParseAgeRecipient("age1xmwwc06ly3ee5rytxm9mflaz2u56jjj36s0mypdrwsvlul66mv4q47ryef")
+	require.NoError(t, err)
+	require.Equal(t, privateKey.Public(), publicKey)
+
+	privateKey = GenerateKeyFrom(reader)
+	parsedPrivateKey, err := ParseAgeIdentity(privateKey.AgeIdentity())
+	require.NoError(t, err)
+	require.Equal(t, privateKey, parsedPrivateKey)
+	parsedPublicKey, err := ParseAgeRecipient(privateKey.Public().AgeRecipient())
+	require.NoError(t, err)
+	require.Equal(t, privateKey.Public(), parsedPublicKey)
+
+	for _, s := range []string{
+		strings.ToUpper(privateKey.Public().AgeRecipient()),
+		strings.ToLower(privateKey.AgeIdentity()),
+		bech32Encode("age", privateKey.Public()[:31]),
+		bech32Encode("age", make([]byte, 33)),
+		bech32Encode("agf", privateKey.Public()[:]),
+		privateKey.AgeIdentity(),
+	} {
+		_, err = ParseAgeRecipient(s)
+		require.Error(t, err, s)
+	}
+	for _, s := range []string{
+		privateKey.Public().AgeRecipient(),
+		// with a Kelvin sign for a K, which Unicode folds to k
+		"AGE-SECRET-KEY-1EGTZVFFV20835NWYV6270LXYV\u212a2VKNX2MMDKWYKLMGR48UAWX40Q2P2LM0",
+		bech32Encode("AGE-SECRET-KEY-", make([]byte, 31)),
+		bech32Encode("AGE-PLUGIN-FOO-", privateKey.Bytes()),
+	} {
+		_, err = ParseAgeIdentity(s)
+		require.Error(t, err, s)
+	}
+}
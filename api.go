@@ -2,13 +2,31 @@ package curve25519
 
 import (
 	"bytes"
+	"crypto"
+	"crypto/ed25519"
 	"crypto/rand"
 	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
 	"io"
 
 	goCurve25519 "golang.org/x/crypto/curve25519"
 )
 
+var (
+	// ErrInvalidLength is returned when a key or signature has the wrong size.
+	ErrInvalidLength = errors.New("curve25519: invalid length")
+	// ErrNonCanonical is returned when a public key or signature isn't in its
+	// canonical encoding, i.e. it is not fully reduced.
+	ErrNonCanonical = errors.New("curve25519: non-canonical encoding")
+	// ErrLowOrderPoint is returned when a peer's public key is a point of
+	// small order, which would make the shared secret all zeros.
+	ErrLowOrderPoint = errors.New("curve25519: low-order public key")
+)
+
 type Signature [64]byte
 
 func NewSignature(bytes []byte) (s *Signature) {
@@ -17,10 +35,41 @@ func NewSignature(bytes []byte) (s *Signature) {
 	return
 }
 
+func ParseSignature(bytes []byte) (s *Signature, err error) {
+	if len(bytes) != 64 {
+		return nil, ErrInvalidLength
+	}
+	s = NewSignature(bytes)
+	if !s.isCanonical() {
+		return nil, ErrNonCanonical
+	}
+	return
+}
+
+func (s *Signature) MarshalBinary() ([]byte, error) {
+	return append([]byte(nil), s[:]...), nil
+}
+
+func (s *Signature) UnmarshalBinary(data []byte) error {
+	if len(data) != 64 {
+		return ErrInvalidLength
+	}
+	copy(s[:], data)
+	return nil
+}
+
 func (s *Signature) isCanonical() bool {
 	return isCanonicalSignature(s[:])
 }
 
+type VRFProof [96]byte
+
+func NewVRFProof(bytes []byte) (p *VRFProof) {
+	p = new(VRFProof)
+	copy(p[:], bytes)
+	return
+}
+
 type PublicKey [32]byte
 
 func NewPublicKey(bytes []byte) (pk *PublicKey) {
@@ -29,10 +78,63 @@ func NewPublicKey(bytes []byte) (pk *PublicKey) {
 	return
 }
 
+func ParsePublicKey(bytes []byte) (pk *PublicKey, err error) {
+	if len(bytes) != 32 {
+		return nil, ErrInvalidLength
+	}
+	pk = NewPublicKey(bytes)
+	if !pk.isCanonical() {
+		return nil, ErrNonCanonical
+	}
+	return
+}
+
+func NewPublicKeyFromEd25519(edPublicKey ed25519.PublicKey) (pk *PublicKey, err error) {
+	if len(edPublicKey) != ed25519.PublicKeySize {
+		return nil, errors.New("curve25519: invalid Ed25519 public key length")
+	}
+	pk = new(PublicKey)
+	if !edwardsToMontgomery(pk[:], edPublicKey) {
+		return nil, errors.New("curve25519: invalid Ed25519 public key")
+	}
+	return
+}
+
+func (pk *PublicKey) Equal(x crypto.PublicKey) bool {
+	other, ok := x.(*PublicKey)
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare(pk[:], other[:]) == 1
+}
+
+func (pk *PublicKey) MarshalBinary() ([]byte, error) {
+	return append([]byte(nil), pk[:]...), nil
+}
+
+func (pk *PublicKey) UnmarshalBinary(data []byte) error {
+	if len(data) != 32 {
+		return ErrInvalidLength
+	}
+	copy(pk[:], data)
+	return nil
+}
+
 func (pk *PublicKey) isCanonical() bool {
 	return isCanonicalPublicKey(pk[:])
 }
 
+func (pk *PublicKey) ToEd25519(signBit byte) (ed25519.PublicKey, error) {
+	if signBit > 1 {
+		return nil, errors.New("curve25519: sign bit must be 0 or 1")
+	}
+	edPublicKey := make(ed25519.PublicKey, ed25519.PublicKeySize)
+	if !montgomeryToEdwards(edPublicKey, pk[:], int(signBit)) {
+		return nil, errors.New("curve25519: public key has no Ed25519 equivalent")
+	}
+	return edPublicKey, nil
+}
+
 type PrivateKey struct {
 	raw [32]byte
 }
@@ -42,14 +144,22 @@ func GenerateKey() (sk *PrivateKey) {
 }
 
 func GenerateKeyFrom(reader io.Reader) (sk *PrivateKey) {
-	sk = new(PrivateKey)
-	if _, err := io.ReadFull(reader, sk.raw[:]); err != nil {
+	sk, err := GenerateKeyWithReader(reader)
+	if err != nil {
 		panic(err)
 	}
-	clamp(sk.raw[:])
 	return sk
 }
 
+func GenerateKeyWithReader(reader io.Reader) (sk *PrivateKey, err error) {
+	sk = new(PrivateKey)
+	if _, err = io.ReadFull(reader, sk.raw[:]); err != nil {
+		return nil, fmt.Errorf("curve25519: failed to read random bytes: %w", err)
+	}
+	clamp(sk.raw[:])
+	return sk, nil
+}
+
 func NewPrivateKey(bytes []byte) (sk *PrivateKey) {
 	sk = new(PrivateKey)
 	copy(sk.raw[:], bytes)
@@ -57,11 +167,43 @@ func NewPrivateKey(bytes []byte) (sk *PrivateKey) {
 	return sk
 }
 
-func (sk *PrivateKey) toBytes(bytes []byte) []byte {
-	if bytes == nil {
-		bytes = make([]byte, 32)
+func ParsePrivateKey(bytes []byte) (sk *PrivateKey, err error) {
+	if len(bytes) != 32 {
+		return nil, ErrInvalidLength
 	}
-	copy(bytes, sk.raw[:])
+	return NewPrivateKey(bytes), nil
+}
+
+func NewPrivateKeyFromEd25519(edPrivateKey ed25519.PrivateKey) (sk *PrivateKey, err error) {
+	if len(edPrivateKey) != ed25519.PrivateKeySize {
+		return nil, errors.New("curve25519: invalid Ed25519 private key length")
+	}
+	digest := sha512.Sum512(edPrivateKey.Seed())
+	return NewPrivateKey(digest[:32]), nil
+}
+
+// Bytes returns a copy of the 32-byte clamped private key.
+func (sk *PrivateKey) Bytes() []byte {
+	return sk.AppendBytes(make([]byte, 0, 32))
+}
+
+// AppendBytes appends the 32-byte clamped private key to dst.
+func (sk *PrivateKey) AppendBytes(dst []byte) []byte {
+	return append(dst, sk.raw[:]...)
+}
+
+func (sk *PrivateKey) MarshalBinary() ([]byte, error) {
+	return sk.Bytes(), nil
+}
+
+// UnmarshalBinary sets sk to the 32-byte private key in data, clamping it as
+// NewPrivateKey does.
+func (sk *PrivateKey) UnmarshalBinary(data []byte) error {
+	if len(data) != 32 {
+		return ErrInvalidLength
+	}
+	copy(sk.raw[:], data)
+	clamp(sk.raw[:])
 	return nil
 }
 
@@ -71,12 +213,67 @@ func (sk *PrivateKey) Public() (pk *PublicKey) {
 	return
 }
 
+func (sk *PrivateKey) Equal(x crypto.PrivateKey) bool {
+	other, ok := x.(*PrivateKey)
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare(sk.raw[:], other.raw[:]) == 1
+}
+
+// CryptoSigner returns sk as a crypto.Signer, whose Public method returns a
+// *PublicKey and whose Sign method makes EC-KCDSA signatures.
+//
+// Like crypto/ed25519, if opts.HashFunc() is zero the digest passed to Sign is
+// the message itself, which is signed as Sign does.  Otherwise it must be the
+// digest of the message under that hash, which is signed as SignWithOptions
+// does, so *SignerOptions can be passed as opts.
+func (sk *PrivateKey) CryptoSigner() crypto.Signer {
+	return cryptoSigner{sk}
+}
+
+type cryptoSigner struct {
+	sk *PrivateKey
+}
+
+func (s cryptoSigner) Public() crypto.PublicKey {
+	return s.sk.Public()
+}
+
+func (s cryptoSigner) Sign(reader io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if opts.HashFunc() == 0 {
+		return s.sk.Sign(digest)[:], nil
+	}
+	h, err := signatureHash(opts.HashFunc())
+	if err != nil {
+		return nil, err
+	}
+	if len(digest) != h.Size() {
+		return nil, errors.New("curve25519: digest length doesn't match the hash function")
+	}
+	return s.sk.signDigest(h, digest)[:], nil
+}
+
 func (sk *PrivateKey) SharedSecret(pk *PublicKey) []byte {
 	var ss [32]byte
 	goCurve25519.ScalarMult(&ss, &sk.raw, (*[32]byte)(pk))
 	return ss[:]
 }
 
+// SharedSecretChecked is like SharedSecret, but fails with ErrLowOrderPoint
+// rather than returning a shared secret that the peer can predict without
+// knowing sk, i.e. the all-zero output of a low-order public key.
+func (sk *PrivateKey) SharedSecretChecked(pk *PublicKey) ([]byte, error) {
+	if isLowOrderPoint(pk[:]) {
+		return nil, ErrLowOrderPoint
+	}
+	ss := sk.SharedSecret(pk)
+	if isZeroSharedSecret(ss) {
+		return nil, ErrLowOrderPoint
+	}
+	return ss, nil
+}
+
 func (sk *PrivateKey) myPublic() (pk *PublicKey) {
 	pk = new(PublicKey)
 	keygen(pk[:], nil, sk.raw[:])
@@ -89,34 +286,101 @@ func (sk *PrivateKey) mySharedSecret(pk *PublicKey) (ss []byte) {
 	return
 }
 
+func (sk *PrivateKey) mySharedSecretChecked(pk *PublicKey) ([]byte, error) {
+	if isLowOrderPoint(pk[:]) {
+		return nil, ErrLowOrderPoint
+	}
+	/* like x/crypto, ignore the top bit as RFC 7748 requires */
+	masked := *pk
+	masked[31] &= 0x7F
+	ss := sk.mySharedSecret(&masked)
+	if isZeroSharedSecret(ss) {
+		return nil, ErrLowOrderPoint
+	}
+	return ss, nil
+}
+
+// SignerOptions selects the hash function used for EC-KCDSA signatures.  The
+// zero value selects SHA-256, which gives the same signatures as Sign.
+type SignerOptions struct {
+	Hash crypto.Hash
+}
+
+func (opts *SignerOptions) HashFunc() crypto.Hash {
+	return opts.Hash
+}
+
+// VerifyOptions selects the hash function used for verifying EC-KCDSA
+// signatures and whether non-canonical signatures and keys are rejected.
+type VerifyOptions struct {
+	Hash             crypto.Hash
+	EnforceCanonical bool
+}
+
 func (sk *PrivateKey) Sign(message []byte) (signature *Signature) {
+	hash := sha256.New()
+	hash.Write(message)
+	return sk.signDigest(crypto.SHA256, hash.Sum(nil))
+}
+
+func (sk *PrivateKey) SignWithContext(context, message []byte) (signature *Signature) {
+	return sk.signDigest(crypto.SHA256, contextDigest(context, message))
+}
+
+func (sk *PrivateKey) SignWithOptions(message []byte, opts *SignerOptions) (signature *Signature, err error) {
+	if opts == nil {
+		opts = new(SignerOptions)
+	}
+	h, err := signatureHash(opts.Hash)
+	if err != nil {
+		return nil, err
+	}
+	hash := h.New()
+	hash.Write(message)
+	return sk.signDigest(h, hash.Sum(nil)), nil
+}
+
+func (sk *PrivateKey) signDigest(h crypto.Hash, messageDigest []byte) (signature *Signature) {
 	publicKey := make([]byte, 32)
 	signingKey := make([]byte, 32)
 	keygen(publicKey, signingKey, sk.raw[:])
 
-	hash := sha256.New()
-	hash.Write(message)
-	messageDigest := hash.Sum(nil)
-
-	hash.Reset()
-	hash.Write(messageDigest)
-	hash.Write(signingKey)
-	x := hash.Sum(nil)
+	x := signatureDigest(h, messageDigest, signingKey)
 	y := make([]byte, 32)
 	keygen(y, nil, x)
 
-	hash.Reset()
-	hash.Write(messageDigest)
-	hash.Write(y)
-	h := hash.Sum(nil)
+	hh := signatureDigest(h, messageDigest, y)
 
 	signature = new(Signature)
-	sign(signature[:], h, x, signingKey)
-	copy(signature[32:], h)
+	sign(signature[:], hh, x, signingKey)
+	copy(signature[32:], hh)
 	return
 }
 
 func Verify(message []byte, signature *Signature, pk *PublicKey, enforceCanonical bool) bool {
+	hash := sha256.New()
+	hash.Write(message)
+	return verifyDigest(crypto.SHA256, hash.Sum(nil), signature, pk, enforceCanonical)
+}
+
+func VerifyWithContext(context, message []byte, signature *Signature, pk *PublicKey, enforceCanonical bool) bool {
+	return verifyDigest(crypto.SHA256, contextDigest(context, message), signature, pk, enforceCanonical)
+}
+
+func VerifyWithOptions(message []byte, signature *Signature, pk *PublicKey, opts *VerifyOptions) bool {
+	if opts == nil {
+		opts = new(VerifyOptions)
+	}
+	h, err := signatureHash(opts.Hash)
+	if err != nil {
+		return false
+	}
+	hash := h.New()
+	hash.Write(message)
+	return verifyDigest(h, hash.Sum(nil), signature, pk, opts.EnforceCanonical)
+}
+
+func verifyDigest(h crypto.Hash, messageDigest []byte, signature *Signature, pk *PublicKey, enforceCanonical bool) bool {
 	if enforceCanonical {
 		if !signature.isCanonical() {
 			return false
@@ -128,18 +392,85 @@ func Verify(message []byte, signature *Signature, pk *PublicKey, enforceCanonica
 
 	Y := make([]byte, 32)
 	v := signature[:32]
-	h := signature[32:]
+	hh := signature[32:]
 
-	verify(Y, v, h, pk[:])
+	verify(Y, v, hh, pk[:])
+
+	return bytes.Equal(hh, signatureDigest(h, messageDigest, Y))
+}
+
+func signatureHash(h crypto.Hash) (crypto.Hash, error) {
+	if h == 0 {
+		return crypto.SHA256, nil
+	}
+	if !h.Available() {
+		return 0, errors.New("curve25519: hash function is not available")
+	}
+	if h.Size() < 32 {
+		return 0, errors.New("curve25519: hash function is shorter than 256 bits")
+	}
+	return h, nil
+}
+
+/* hash the concatenation of parts into 32 bytes, digests wider than that are
+ * reduced modulo the group order */
+func signatureDigest(h crypto.Hash, parts ...[]byte) []byte {
+	hash := h.New()
+	for _, part := range parts {
+		hash.Write(part)
+	}
+	digest := hash.Sum(nil)
+	if len(digest) > 32 {
+		reduce(digest, digest)
+		digest = digest[:32]
+	}
+	return digest
+}
+
+/* m = hash(Z, message), where the context data Z is prefixed with its length
+ * so that no other context and message pair can produce the same m */
+func contextDigest(context, message []byte) []byte {
+	length := make([]byte, 8)
+	binary.BigEndian.PutUint64(length, uint64(len(context)))
 
 	hash := sha256.New()
+	hash.Write(length)
+	hash.Write(context)
 	hash.Write(message)
-	messageDigest := hash.Sum(nil)
+	return hash.Sum(nil)
+}
 
-	hash.Reset()
-	hash.Write(messageDigest)
-	hash.Write(Y)
-	h2 := hash.Sum(nil)
+func (sk *PrivateKey) SignXEdDSA(reader io.Reader, message []byte) (signature *Signature, err error) {
+	random := make([]byte, 64)
+	if _, err = io.ReadFull(reader, random); err != nil {
+		return nil, err
+	}
+
+	signature = new(Signature)
+	xeddsaSign(signature[:], sk.raw[:], message, random)
+	return
+}
 
-	return bytes.Equal(h, h2)
+func VerifyXEdDSA(pk *PublicKey, message []byte, signature *Signature) bool {
+	return xeddsaVerify(pk[:], message, signature[:])
+}
+
+func (sk *PrivateKey) Prove(reader io.Reader, message []byte) (output []byte, proof *VRFProof, err error) {
+	random := make([]byte, 64)
+	if _, err = io.ReadFull(reader, random); err != nil {
+		return nil, nil, err
+	}
+
+	output = make([]byte, 32)
+	proof = new(VRFProof)
+	vxeddsaSign(proof[:], output, sk.raw[:], message, random)
+	return
+}
+
+func VerifyVRF(pk *PublicKey, message []byte, proof *VRFProof) (output []byte, ok bool) {
+	output = make([]byte, 32)
+	if !vxeddsaVerify(output, pk[:], message, proof[:]) {
+		return nil, false
+	}
+	return output, true
 }
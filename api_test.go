@@ -1,7 +1,12 @@
 package curve25519
 
 import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"math/rand"
@@ -11,6 +16,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/require"
+	_ "golang.org/x/crypto/sha3"
 )
 
 var reader = rand.New(rand.NewSource(time.Now().UnixNano()))
@@ -54,17 +60,227 @@ func TestSignature(t *testing.T) {
 		actual := privateKey.Sign(message)
 		require.True(t, actual.isCanonical())
 
-		bytes, err = hex.DecodeString(hexSignature)
-		require.NoError(t, err)
-		expected := NewSignature(bytes)
+		expected := new(Signature)
+		require.NoError(t, expected.UnmarshalText([]byte(hexSignature)))
 
 		require.Equal(t, expected, actual)
 
 		result := Verify(message, actual, privateKey.Public(), true)
 		require.True(t, result)
+
+		actual, err = privateKey.SignWithOptions(message, &SignerOptions{})
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+
+		result = VerifyWithOptions(message, expected, privateKey.Public(), &VerifyOptions{EnforceCanonical: true})
+		require.True(t, result)
 	}
 }
 
+func TestSignatureWithOptions(t *testing.T) {
+	for _, h := range []crypto.Hash{crypto.SHA256, crypto.SHA384, crypto.SHA512, crypto.SHA3_256, crypto.SHA3_512} {
+		for i := 0; i < 100; i++ {
+			privateKey := GenerateKeyFrom(reader)
+			publicKey := privateKey.Public()
+			message := randomBytes(i)
+
+			signature, err := privateKey.SignWithOptions(message, &SignerOptions{Hash: h})
+			require.NoError(t, err)
+			require.True(t, signature.isCanonical())
+			require.True(t, VerifyWithOptions(message, signature, publicKey, &VerifyOptions{Hash: h, EnforceCanonical: true}))
+
+			require.False(t, VerifyWithOptions(append(message, 0), signature, publicKey, &VerifyOptions{Hash: h}))
+			if h != crypto.SHA256 {
+				require.False(t, Verify(message, signature, publicKey, false))
+				require.False(t, VerifyWithOptions(message, signature, publicKey, nil))
+			}
+		}
+	}
+
+	privateKey := GenerateKeyFrom(reader)
+	for _, h := range []crypto.Hash{crypto.SHA224, crypto.SHA1, crypto.MD4} {
+		_, err := privateKey.SignWithOptions(nil, &SignerOptions{Hash: h})
+		require.Error(t, err)
+
+		signature := privateKey.Sign(nil)
+		require.False(t, VerifyWithOptions(nil, signature, privateKey.Public(), &VerifyOptions{Hash: h}))
+	}
+
+	signature, err := privateKey.SignWithOptions(nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, privateKey.Sign(nil), signature)
+}
+
+func TestSignatureWithContext(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		privateKey := GenerateKeyFrom(reader)
+		publicKey := privateKey.Public()
+		message := randomBytes(i + 1)
+		context := []byte("service-a")
+
+		signature := privateKey.SignWithContext(context, message)
+		require.True(t, signature.isCanonical())
+		require.True(t, VerifyWithContext(context, message, signature, publicKey, true))
+
+		require.False(t, VerifyWithContext([]byte("service-b"), message, signature, publicKey, true))
+		require.False(t, VerifyWithContext(nil, message, signature, publicKey, true))
+		require.False(t, Verify(message, signature, publicKey, true))
+		require.False(t, VerifyWithContext(context, message, privateKey.Sign(message), publicKey, true))
+
+		// moving bytes between context and message must not keep the signature valid
+		shifted := append(context, message[0])
+		require.False(t, VerifyWithContext(shifted, message[1:], signature, publicKey, true))
+	}
+
+	privateKey := GenerateKeyFrom(reader)
+	signature := privateKey.SignWithContext(nil, []byte("message"))
+	require.True(t, VerifyWithContext([]byte{}, []byte("message"), signature, privateKey.Public(), true))
+}
+
+func TestCryptoSigner(t *testing.T) {
+	privateKey := GenerateKeyFrom(reader)
+	publicKey := privateKey.Public()
+	signer := privateKey.CryptoSigner()
+
+	require.Equal(t, publicKey, signer.Public())
+	require.True(t, publicKey.Equal(signer.Public()))
+	require.False(t, publicKey.Equal(GenerateKeyFrom(reader).Public()))
+	require.False(t, publicKey.Equal(ed25519.PublicKey(publicKey[:])))
+	require.False(t, publicKey.Equal(*publicKey))
+
+	require.True(t, privateKey.Equal(NewPrivateKey(privateKey.raw[:])))
+	require.False(t, privateKey.Equal(GenerateKeyFrom(reader)))
+	require.False(t, privateKey.Equal(privateKey.raw))
+
+	message := randomBytes(100)
+
+	signature, err := signer.Sign(reader, message, crypto.Hash(0))
+	require.NoError(t, err)
+	require.Equal(t, privateKey.Sign(message)[:], signature)
+
+	digest := sha256.Sum256(message)
+	signature, err = signer.Sign(reader, digest[:], crypto.SHA256)
+	require.NoError(t, err)
+	require.Equal(t, privateKey.Sign(message)[:], signature)
+
+	digest512 := sha512.Sum512(message)
+	opts := &SignerOptions{Hash: crypto.SHA512}
+	signature, err = signer.Sign(reader, digest512[:], opts)
+	require.NoError(t, err)
+	expected, err := privateKey.SignWithOptions(message, opts)
+	require.NoError(t, err)
+	require.Equal(t, expected[:], signature)
+	require.True(t, VerifyWithOptions(message, NewSignature(signature), publicKey, &VerifyOptions{Hash: crypto.SHA512}))
+
+	_, err = signer.Sign(reader, digest[:], crypto.SHA512)
+	require.Error(t, err)
+	_, err = signer.Sign(reader, digest[:20], crypto.SHA1)
+	require.Error(t, err)
+}
+
+func TestParse(t *testing.T) {
+	privateKey := GenerateKeyFrom(reader)
+	publicKey := privateKey.Public()
+	signature := privateKey.Sign([]byte("message"))
+
+	parsedPrivateKey, err := ParsePrivateKey(privateKey.raw[:])
+	require.NoError(t, err)
+	require.Equal(t, privateKey, parsedPrivateKey)
+
+	unclamped := randomBytes(32)
+	unclamped[0] |= 7
+	parsedPrivateKey, err = ParsePrivateKey(unclamped)
+	require.NoError(t, err)
+	require.Equal(t, NewPrivateKey(unclamped), parsedPrivateKey)
+
+	parsedPublicKey, err := ParsePublicKey(publicKey[:])
+	require.NoError(t, err)
+	require.Equal(t, publicKey, parsedPublicKey)
+
+	parsedSignature, err := ParseSignature(signature[:])
+	require.NoError(t, err)
+	require.Equal(t, signature, parsedSignature)
+
+	for _, n := range []int{0, 31, 33, 64} {
+		_, err = ParsePrivateKey(make([]byte, n))
+		require.Equal(t, ErrInvalidLength, err)
+		_, err = ParsePublicKey(make([]byte, n))
+		require.Equal(t, ErrInvalidLength, err)
+	}
+	for _, n := range []int{0, 32, 63, 65} {
+		_, err = ParseSignature(make([]byte, n))
+		require.Equal(t, ErrInvalidLength, err)
+	}
+
+	// 2^255 - 19 + 1, which reduces to 1
+	nonCanonical := decodeHex(t, "eeffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f")
+	_, err = ParsePublicKey(nonCanonical)
+	require.Equal(t, ErrNonCanonical, err)
+
+	nonCanonical = append(make([]byte, 0, 64), order...)
+	nonCanonical = append(nonCanonical, signature[32:]...)
+	_, err = ParseSignature(nonCanonical)
+	require.Equal(t, ErrNonCanonical, err)
+}
+
+func TestBinaryMarshaling(t *testing.T) {
+	privateKey := GenerateKeyFrom(reader)
+	publicKey := privateKey.Public()
+	signature := privateKey.Sign([]byte("message"))
+
+	require.Equal(t, privateKey.raw[:], privateKey.Bytes())
+	require.Equal(t, append([]byte("prefix"), privateKey.raw[:]...), privateKey.AppendBytes([]byte("prefix")))
+	exported := privateKey.Bytes()
+	exported[0] ^= 0xFF
+	require.NotEqual(t, exported, privateKey.raw[:])
+
+	data, err := privateKey.MarshalBinary()
+	require.NoError(t, err)
+	var unmarshaledPrivateKey PrivateKey
+	require.NoError(t, unmarshaledPrivateKey.UnmarshalBinary(data))
+	require.True(t, privateKey.Equal(&unmarshaledPrivateKey))
+
+	unclamped := randomBytes(32)
+	unclamped[0] |= 7
+	unclamped[31] |= 0x80
+	require.NoError(t, unmarshaledPrivateKey.UnmarshalBinary(unclamped))
+	require.Equal(t, NewPrivateKey(unclamped), &unmarshaledPrivateKey)
+
+	data, err = publicKey.MarshalBinary()
+	require.NoError(t, err)
+	var unmarshaledPublicKey PublicKey
+	require.NoError(t, unmarshaledPublicKey.UnmarshalBinary(data))
+	require.Equal(t, publicKey, &unmarshaledPublicKey)
+
+	data, err = signature.MarshalBinary()
+	require.NoError(t, err)
+	var unmarshaledSignature Signature
+	require.NoError(t, unmarshaledSignature.UnmarshalBinary(data))
+	require.Equal(t, signature, &unmarshaledSignature)
+
+	for _, n := range []int{0, 31, 33, 64} {
+		require.Equal(t, ErrInvalidLength, unmarshaledPrivateKey.UnmarshalBinary(make([]byte, n)))
+		require.Equal(t, ErrInvalidLength, unmarshaledPublicKey.UnmarshalBinary(make([]byte, n)))
+	}
+	for _, n := range []int{0, 32, 63, 65} {
+		require.Equal(t, ErrInvalidLength, unmarshaledSignature.UnmarshalBinary(make([]byte, n)))
+	}
+}
+
+func TestGenerateKeyWithReader(t *testing.T) {
+	privateKey, err := GenerateKeyWithReader(reader)
+	require.NoError(t, err)
+	require.True(t, privateKey.Public().isCanonical())
+
+	_, err = GenerateKeyWithReader(io.LimitReader(reader, 31))
+	require.Error(t, err)
+	require.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+
+	require.Panics(t, func() {
+		GenerateKeyFrom(io.LimitReader(reader, 31))
+	})
+}
+
 func TestMine(t *testing.T) {
 	for i := 0; i < 1000; i++ {
 		privateKey := GenerateKeyFrom(reader)
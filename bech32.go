@@ -0,0 +1,128 @@
+package curve25519
+
+import (
+	"errors"
+	"strings"
+)
+
+/* Bech32 of BIP 173, without its 90-character limit, as age uses it */
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+var bech32Generator = []uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+
+func bech32Polymod(values []byte) uint32 {
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i, g := range bech32Generator {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= g
+			}
+		}
+	}
+	return chk
+}
+
+func bech32HRPExpand(hrp string) []byte {
+	expanded := make([]byte, 0, 2*len(hrp)+1)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]>>5)
+	}
+	expanded = append(expanded, 0)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]&31)
+	}
+	return expanded
+}
+
+/* regroups bits, padding the last group with zeros when encoding and
+ * rejecting any padding but that when decoding */
+func convertBits(data []byte, from, to uint, pad bool) ([]byte, error) {
+	var converted []byte
+	acc, bits := uint32(0), uint(0)
+	maxv := uint32(1)<<to - 1
+	for _, b := range data {
+		if uint32(b)>>from != 0 {
+			return nil, errors.New("curve25519: invalid Bech32 data")
+		}
+		acc = acc<<from | uint32(b)
+		bits += from
+		for bits >= to {
+			bits -= to
+			converted = append(converted, byte(acc>>bits&maxv))
+		}
+	}
+	if pad {
+		if bits > 0 {
+			converted = append(converted, byte(acc<<(to-bits)&maxv))
+		}
+	} else if bits >= from || acc<<(to-bits)&maxv != 0 {
+		return nil, errors.New("curve25519: invalid Bech32 padding")
+	}
+	return converted, nil
+}
+
+/* encodes data under hrp, in the case of hrp, which must not be mixed */
+func bech32Encode(hrp string, data []byte) string {
+	lower := strings.ToLower(hrp)
+	values, _ := convertBits(data, 8, 5, true)
+	checksum := bech32Polymod(append(append(bech32HRPExpand(lower), values...), 0, 0, 0, 0, 0, 0)) ^ 1
+	for i := 0; i < 6; i++ {
+		values = append(values, byte(checksum>>uint(5*(5-i))&31))
+	}
+
+	var b strings.Builder
+	b.WriteString(lower)
+	b.WriteByte('1')
+	for _, v := range values {
+		b.WriteByte(bech32Charset[v])
+	}
+	if hrp != lower {
+		return strings.ToUpper(b.String())
+	}
+	return b.String()
+}
+
+/* decodes s, returning its hrp in the case of s.  Only ASCII is folded, so
+ * that no other rune can stand in for a letter of the charset */
+func bech32Decode(s string) (hrp string, data []byte, err error) {
+	lower := make([]byte, len(s))
+	hasLower, hasUpper := false, false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 33 || c > 126 {
+			return "", nil, errors.New("curve25519: invalid Bech32 character")
+		}
+		if c >= 'a' && c <= 'z' {
+			hasLower = true
+		} else if c >= 'A' && c <= 'Z' {
+			hasUpper = true
+			c += 'a' - 'A'
+		}
+		lower[i] = c
+	}
+	if hasLower && hasUpper {
+		return "", nil, errors.New("curve25519: mixed-case Bech32 string")
+	}
+	pos := strings.LastIndexByte(s, '1')
+	if pos < 1 || pos+7 > len(s) {
+		return "", nil, errors.New("curve25519: malformed Bech32 string")
+	}
+	values := make([]byte, 0, len(s)-pos-1)
+	for i := pos + 1; i < len(s); i++ {
+		v := strings.IndexByte(bech32Charset, lower[i])
+		if v < 0 {
+			return "", nil, errors.New("curve25519: invalid Bech32 character")
+		}
+		values = append(values, byte(v))
+	}
+	if bech32Polymod(append(bech32HRPExpand(string(lower[:pos])), values...)) != 1 {
+		return "", nil, errors.New("curve25519: invalid Bech32 checksum")
+	}
+	if data, err = convertBits(values[:len(values)-6], 5, 8, false); err != nil {
+		return "", nil, err
+	}
+	return s[:pos], data, nil
+}
@@ -0,0 +1,114 @@
+package curve25519
+
+import (
+	"io"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/nacl/box"
+	"golang.org/x/crypto/salsa20/salsa"
+)
+
+// BoxOverhead is the number of bytes by which a box is longer than its
+// message, and AnonymousOverhead that of an anonymous (sealed) box.
+const (
+	BoxOverhead       = box.Overhead
+	AnonymousOverhead = 32 + box.Overhead
+)
+
+// BoxKey is the key shared by two parties for boxes between them, as computed
+// by crypto_box_beforenm.
+type BoxKey [32]byte
+
+// PrecomputeBox returns the key shared by sk and pk, for the AfterPrecomputation
+// variants of Box and OpenBox, which are faster when boxing many messages to
+// the same peer.  Like libsodium, it fails with ErrLowOrderPoint rather than
+// use an all-zero X25519 output.
+func (sk *PrivateKey) PrecomputeBox(pk *PublicKey) (key *BoxKey, err error) {
+	ss, err := sk.SharedSecretChecked(pk)
+	if err != nil {
+		return nil, err
+	}
+	key = new(BoxKey)
+	var in [16]byte
+	var k [32]byte
+	copy(k[:], ss)
+	salsa.HSalsa20((*[32]byte)(key), &in, &k, &salsa.Sigma)
+	return
+}
+
+// Box encrypts and authenticates message from sk to pk, as libsodium's
+// crypto_box_easy does, appending the result to out.  The nonce must be
+// unique for each message between the same keys.
+func Box(out, message []byte, nonce *[24]byte, pk *PublicKey, sk *PrivateKey) ([]byte, error) {
+	key, err := sk.PrecomputeBox(pk)
+	if err != nil {
+		return nil, err
+	}
+	return BoxAfterPrecomputation(out, message, nonce, key), nil
+}
+
+// OpenBox authenticates and decrypts a box made by Box from pk to sk, as
+// crypto_box_open_easy does, appending the message to out.  It fails with
+// ErrOpen if the box isn't authentic.
+func OpenBox(out, boxed []byte, nonce *[24]byte, pk *PublicKey, sk *PrivateKey) ([]byte, error) {
+	key, err := sk.PrecomputeBox(pk)
+	if err != nil {
+		return nil, err
+	}
+	return OpenBoxAfterPrecomputation(out, boxed, nonce, key)
+}
+
+// BoxAfterPrecomputation is Box with a key from PrecomputeBox.
+func BoxAfterPrecomputation(out, message []byte, nonce *[24]byte, key *BoxKey) []byte {
+	return box.SealAfterPrecomputation(out, message, nonce, (*[32]byte)(key))
+}
+
+// OpenBoxAfterPrecomputation is OpenBox with a key from PrecomputeBox.
+func OpenBoxAfterPrecomputation(out, boxed []byte, nonce *[24]byte, key *BoxKey) ([]byte, error) {
+	message, ok := box.OpenAfterPrecomputation(out, boxed, nonce, (*[32]byte)(key))
+	if !ok {
+		return nil, ErrOpen
+	}
+	return message, nil
+}
+
+// SealAnonymous encrypts message to pk from an ephemeral key generated from
+// reader, as libsodium's crypto_box_seal does, appending the result to out.
+// The recipient can't tell who sent it.
+func SealAnonymous(reader io.Reader, out, message []byte, pk *PublicKey) ([]byte, error) {
+	ephemeral, err := GenerateKeyWithReader(reader)
+	if err != nil {
+		return nil, err
+	}
+	ephemeralPublic := ephemeral.Public()
+	key, err := ephemeral.PrecomputeBox(pk)
+	if err != nil {
+		return nil, err
+	}
+	out = append(out, ephemeralPublic[:]...)
+	return BoxAfterPrecomputation(out, message, anonymousNonce(ephemeralPublic, pk), key), nil
+}
+
+// OpenAnonymous decrypts a box made by SealAnonymous for the public key of sk,
+// as crypto_box_seal_open does, appending the message to out.
+func OpenAnonymous(out, boxed []byte, sk *PrivateKey) ([]byte, error) {
+	if len(boxed) < AnonymousOverhead {
+		return nil, ErrOpen
+	}
+	ephemeralPublic := NewPublicKey(boxed[:32])
+	key, err := sk.PrecomputeBox(ephemeralPublic)
+	if err != nil {
+		return nil, err
+	}
+	return OpenBoxAfterPrecomputation(out, boxed[32:], anonymousNonce(ephemeralPublic, sk.Public()), key)
+}
+
+/* nonce = blake2b(ephemeral public key || recipient public key), 24 bytes */
+func anonymousNonce(ephemeralPublic, pk *PublicKey) *[24]byte {
+	h, _ := blake2b.New(24, nil)
+	h.Write(ephemeralPublic[:])
+	h.Write(pk[:])
+	nonce := new([24]byte)
+	copy(nonce[:], h.Sum(nil))
+	return nonce
+}
@@ -0,0 +1,89 @@
+package curve25519
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBox(t *testing.T) {
+	// generated with NaCl, from the x/crypto nacl/box tests
+	privateKey1 := NewPrivateKey(bytes.Repeat([]byte{1}, 32))
+	privateKey2 := NewPrivateKey(bytes.Repeat([]byte{2}, 32))
+	message := bytes.Repeat([]byte{3}, 64)
+	var nonce [24]byte
+	copy(nonce[:], bytes.Repeat([]byte{4}, 24))
+	expected := decodeHex(t, "78ea30b19d2341ebbdba54180f821eec265cf86312549bea8a37652a8bb94f07b78a73ed1708085e6ddd0e943bbdeb8755079a37eb31d86163ce241164a47629c0539f330b4914cd135b3855bc2a2dfc")
+
+	boxed, err := Box([]byte("prefix"), message, &nonce, privateKey1.Public(), privateKey2)
+	require.NoError(t, err)
+	require.Equal(t, append([]byte("prefix"), expected...), boxed)
+	require.Len(t, expected, len(message)+BoxOverhead)
+
+	opened, err := OpenBox(nil, expected, &nonce, privateKey2.Public(), privateKey1)
+	require.NoError(t, err)
+	require.Equal(t, message, opened)
+
+	key1, err := privateKey1.PrecomputeBox(privateKey2.Public())
+	require.NoError(t, err)
+	key2, err := privateKey2.PrecomputeBox(privateKey1.Public())
+	require.NoError(t, err)
+	require.Equal(t, key1, key2)
+	require.Equal(t, expected, BoxAfterPrecomputation(nil, message, &nonce, key2))
+	opened, err = OpenBoxAfterPrecomputation(nil, expected, &nonce, key1)
+	require.NoError(t, err)
+	require.Equal(t, message, opened)
+
+	for _, i := range []int{0, 16, len(expected) - 1} {
+		tampered := append([]byte{}, expected...)
+		tampered[i] ^= 1
+		_, err = OpenBox(nil, tampered, &nonce, privateKey2.Public(), privateKey1)
+		require.Equal(t, ErrOpen, err)
+	}
+	_, err = OpenBox(nil, expected[:BoxOverhead-1], &nonce, privateKey2.Public(), privateKey1)
+	require.Equal(t, ErrOpen, err)
+
+	for _, point := range lowOrderPoints {
+		_, err = Box(nil, message, &nonce, NewPublicKey(point), privateKey1)
+		require.Equal(t, ErrLowOrderPoint, err)
+		_, err = OpenBox(nil, expected, &nonce, NewPublicKey(point), privateKey1)
+		require.Equal(t, ErrLowOrderPoint, err)
+	}
+}
+
+func TestSealAnonymous(t *testing.T) {
+	// generated with libsodium, from the x/crypto nacl/box tests
+	privateKey := NewPrivateKey(bytes.Repeat([]byte{1}, 32))
+	message := bytes.Repeat([]byte{3}, 64)
+
+	// with randombytes always returning 5
+	boxed, err := SealAnonymous(bytes.NewReader(bytes.Repeat([]byte{5}, 32)), nil, message, privateKey.Public())
+	require.NoError(t, err)
+	require.Equal(t, decodeHex(t, "50a61409b1ddd0325e9b16b700e719e9772c07000b1bd7786e907c653d20495d2af1697137a53b1b1dfc9befc49b6eeb38f86be720e155eb2be61976d2efb34d67ecd44a6ad634625eb9c288bfc883431a84ab0f5557dfe673aa6f74c19f033e648a947358cfcc606397fa1747d5219a"), boxed)
+	require.Len(t, boxed, len(message)+AnonymousOverhead)
+
+	boxed = decodeHex(t, "3462e0640728247a6f581e3812850d6edc3dcad1ea5d8184c072f62fb65cb357e27ffa8b76f41656bc66a0882c4d359568410665746d27462a700f01e314f382edd7aae9064879b0f8ba7b88866f88f5e4fbd7649c850541877f9f33ebd25d46d9cbcce09b69a9ba07f0eb1d105d4264")
+	opened, err := OpenAnonymous([]byte("prefix"), boxed, privateKey)
+	require.NoError(t, err)
+	require.Equal(t, append([]byte("prefix"), message...), opened)
+
+	_, err = OpenAnonymous(nil, boxed, GenerateKeyFrom(reader))
+	require.Equal(t, ErrOpen, err)
+	_, err = OpenAnonymous(nil, boxed[:AnonymousOverhead-1], privateKey)
+	require.Equal(t, ErrOpen, err)
+	tampered := append([]byte{}, boxed...)
+	tampered[0] ^= 1
+	_, err = OpenAnonymous(nil, tampered, privateKey)
+	require.Equal(t, ErrOpen, err)
+
+	for i := 0; i < 100; i++ {
+		privateKey := GenerateKeyFrom(reader)
+		message := randomBytes(i)
+		boxed, err := SealAnonymous(reader, nil, message, privateKey.Public())
+		require.NoError(t, err)
+		opened, err := OpenAnonymous(nil, boxed, privateKey)
+		require.NoError(t, err)
+		require.Equal(t, message, append([]byte{}, opened...))
+	}
+}
@@ -1,6 +1,9 @@
 package curve25519
 
-import "bytes"
+import (
+	"bytes"
+	"crypto/subtle"
+)
 
 /* group order (a prime near 2^252+2^124) */
 var order = []byte{
@@ -18,6 +21,34 @@ var orderTimes8 = []byte{
 	0, 0, 0, 0, 0, 0, 0, 128,
 }
 
+/* u-coordinates of the eight points of order dividing 8 (the point at
+ * infinity is represented by 0), and their non-canonical encodings, as in
+ * libsodium's blocklist.  u = -1 isn't on the curve but on its twist, where it
+ * has order 2, and gives an all-zero shared secret as well. */
+var lowOrderPoints = [][]byte{
+	/* 0 (order 1 and 2) */
+	{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+	/* 1 (order 4) */
+	{1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+	/* order 8 */
+	{224, 235, 122, 124, 59, 65, 184, 174, 22, 86, 227, 250, 241, 159, 196, 106,
+		218, 9, 141, 235, 156, 50, 177, 253, 134, 98, 5, 22, 95, 73, 184, 0},
+	/* order 8 */
+	{95, 156, 149, 188, 163, 80, 140, 36, 177, 208, 177, 85, 156, 131, 239, 91,
+		4, 68, 92, 196, 88, 28, 142, 134, 216, 34, 78, 221, 208, 159, 17, 87},
+	/* p - 1 */
+	{236, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255,
+		255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 127},
+	/* p, equivalent to 0 */
+	{237, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255,
+		255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 127},
+	/* p + 1, equivalent to 1 */
+	{238, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255,
+		255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 127},
+}
+
 /* Private key clamping
  *   k [out] your private key for key agreement
  *   k [in]  32 random bytes
@@ -49,6 +80,27 @@ func curve(Z, k, P []byte) {
 	core(Z, nil, k, P)
 }
 
+/* Checks in constant time whether P is one of the low-order points, ignoring
+ * the top bit which key agreement masks off anyway */
+func isLowOrderPoint(P []byte) bool {
+	masked := append(P[:0:0], P...)
+	masked[31] &= 0x7F
+	found := 0
+	for _, point := range lowOrderPoints {
+		found |= subtle.ConstantTimeCompare(masked, point)
+	}
+	return found != 0
+}
+
+/* Checks in constant time whether a shared secret is all zeros */
+func isZeroSharedSecret(Z []byte) bool {
+	var acc byte
+	for _, b := range Z {
+		acc |= b
+	}
+	return subtle.ConstantTimeByteEq(acc, 0) == 1
+}
+
 /* P = kG  and s = sign(P)/k  */
 func core(Px, s, k, Gx []byte) {
 	dx := new(long10)
@@ -323,6 +375,10 @@ func verify(Y, v, h, P []byte) {
 }
 
 func isCanonicalSignature(v []byte) bool {
+	return isCanonicalScalar(v)
+}
+
+func isCanonicalScalar(v []byte) bool {
 	if len(v) < 32 {
 		return false
 	}
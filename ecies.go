@@ -0,0 +1,96 @@
+package curve25519
+
+import (
+	"crypto/cipher"
+	"crypto/sha256"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+// SealVersion is the version byte at the start of ciphertexts made by Seal.
+//
+// A ciphertext of version 1 is
+//
+//	version (1 byte) || ephemeral public key (32 bytes) || sealed plaintext
+//
+// The sealed plaintext is the plaintext encrypted with ChaCha20-Poly1305, with
+// the 16-byte tag appended, under an all-zero nonce and a key derived by
+// HKDF-SHA256 from the shared secret of the ephemeral key and the recipient's
+// key, with no salt and with info
+//
+//	"curve25519 ECIES v1" || ephemeral public key || recipient public key
+//
+// The key is only ever used for one message, so the nonce needn't vary.
+const SealVersion = 1
+
+/* version, ephemeral public key and Poly1305 tag */
+const sealOverhead = 1 + 32 + 16
+
+var sealInfo = []byte("curve25519 ECIES v1")
+
+// ErrOpen is returned when a ciphertext can't be decrypted, because it is
+// malformed, was sealed to another key, or was tampered with.
+var ErrOpen = errors.New("curve25519: message authentication failed")
+
+// Seal encrypts and authenticates plaintext to pk, and also authenticates aad,
+// which Open must then be given as well.  A fresh ephemeral key is generated
+// from reader for each message.  The ciphertext format is described in
+// SealVersion.
+func Seal(reader io.Reader, pk *PublicKey, plaintext, aad []byte) (ciphertext []byte, err error) {
+	ephemeral, err := GenerateKeyWithReader(reader)
+	if err != nil {
+		return nil, err
+	}
+	ephemeralPublic := ephemeral.Public()
+	aead, err := sealAEAD(ephemeral, pk, ephemeralPublic, pk)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext = make([]byte, 1+len(ephemeralPublic), sealOverhead+len(plaintext))
+	ciphertext[0] = SealVersion
+	copy(ciphertext[1:], ephemeralPublic[:])
+	return aead.Seal(ciphertext, make([]byte, aead.NonceSize()), plaintext, aad), nil
+}
+
+// Open decrypts a ciphertext made by Seal for the public key of sk, with the
+// same aad.  It fails with ErrLowOrderPoint if the ephemeral key in it is a
+// low-order point, and with ErrOpen if it can't otherwise be decrypted.
+func Open(sk *PrivateKey, ciphertext, aad []byte) (plaintext []byte, err error) {
+	if len(ciphertext) < sealOverhead {
+		return nil, ErrOpen
+	}
+	if ciphertext[0] != SealVersion {
+		return nil, errors.New("curve25519: unsupported ciphertext version")
+	}
+	ephemeralPublic := NewPublicKey(ciphertext[1:33])
+	aead, err := sealAEAD(sk, ephemeralPublic, ephemeralPublic, sk.Public())
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err = aead.Open(nil, make([]byte, aead.NonceSize()), ciphertext[33:], aad)
+	if err != nil {
+		return nil, ErrOpen
+	}
+	return
+}
+
+/* the AEAD keyed for the exchange between sk and pk, where ephemeralPublic and
+ * recipientPublic are the public keys of either side */
+func sealAEAD(sk *PrivateKey, pk, ephemeralPublic, recipientPublic *PublicKey) (cipher.AEAD, error) {
+	ss, err := sk.SharedSecretChecked(pk)
+	if err != nil {
+		return nil, err
+	}
+	info := make([]byte, 0, len(sealInfo)+64)
+	info = append(info, sealInfo...)
+	info = append(info, ephemeralPublic[:]...)
+	info = append(info, recipientPublic[:]...)
+	key := make([]byte, chacha20poly1305.KeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, ss, nil, info), key); err != nil {
+		return nil, err
+	}
+	return chacha20poly1305.New(key)
+}
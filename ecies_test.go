@@ -0,0 +1,85 @@
+package curve25519
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+func TestSeal(t *testing.T) {
+	privateKey := GenerateKeyFrom(reader)
+	publicKey := privateKey.Public()
+
+	for _, n := range []int{0, 1, 64, 1000} {
+		plaintext := randomBytes(n)
+		ciphertext, err := Seal(reader, publicKey, plaintext, []byte("aad"))
+		require.NoError(t, err)
+		require.Len(t, ciphertext, n+sealOverhead)
+		require.Equal(t, byte(SealVersion), ciphertext[0])
+
+		opened, err := Open(privateKey, ciphertext, []byte("aad"))
+		require.NoError(t, err)
+		require.Equal(t, plaintext, append([]byte{}, opened...))
+
+		_, err = Open(privateKey, ciphertext, []byte("other"))
+		require.Equal(t, ErrOpen, err)
+		_, err = Open(GenerateKeyFrom(reader), ciphertext, []byte("aad"))
+		require.Equal(t, ErrOpen, err)
+		for _, i := range []int{1, 33, len(ciphertext) - 1} {
+			tampered := append([]byte{}, ciphertext...)
+			tampered[i] ^= 1
+			_, err = Open(privateKey, tampered, []byte("aad"))
+			require.Error(t, err)
+		}
+		_, err = Open(privateKey, ciphertext[:len(ciphertext)-1], []byte("aad"))
+		require.Equal(t, ErrOpen, err)
+	}
+}
+
+func TestSealFormat(t *testing.T) {
+	privateKey := GenerateKeyFrom(reader)
+	publicKey := privateKey.Public()
+	seed := randomBytes(32)
+	ciphertext, err := Seal(bytes.NewReader(seed), publicKey, []byte("plaintext"), []byte("aad"))
+	require.NoError(t, err)
+
+	// decrypt following the documented format
+	ephemeral := NewPrivateKey(seed)
+	require.Equal(t, ephemeral.Public()[:], ciphertext[1:33])
+	info := append([]byte("curve25519 ECIES v1"), ephemeral.Public()[:]...)
+	info = append(info, publicKey[:]...)
+	key := make([]byte, 32)
+	_, err = io.ReadFull(hkdf.New(sha256.New, ephemeral.SharedSecret(publicKey), nil, info), key)
+	require.NoError(t, err)
+	aead, err := chacha20poly1305.New(key)
+	require.NoError(t, err)
+	plaintext, err := aead.Open(nil, make([]byte, 12), ciphertext[33:], []byte("aad"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("plaintext"), plaintext)
+
+	ciphertext[0] = 2
+	_, err = Open(privateKey, ciphertext, []byte("aad"))
+	require.Error(t, err)
+}
+
+func TestSealLowOrder(t *testing.T) {
+	privateKey := GenerateKeyFrom(reader)
+	for _, point := range lowOrderPoints {
+		_, err := Seal(reader, NewPublicKey(point), []byte("plaintext"), nil)
+		require.Equal(t, ErrLowOrderPoint, err)
+
+		ciphertext := append([]byte{SealVersion}, point...)
+		ciphertext = append(ciphertext, make([]byte, 16)...)
+		_, err = Open(privateKey, ciphertext, nil)
+		require.Equal(t, ErrLowOrderPoint, err)
+	}
+
+	_, err := Seal(io.LimitReader(reader, 31), privateKey.Public(), []byte("plaintext"), nil)
+	require.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+}
@@ -0,0 +1,95 @@
+package curve25519
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func decodeHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
+
+func TestEd25519Conversion(t *testing.T) {
+	// From libsodium's test/default/ed25519_convert.c
+	edPrivateKey := ed25519.NewKeyFromSeed(decodeHex(t, "421151a459faeade3d247115f94aedae42318124095afabe4d1451a559faedee"))
+	edPublicKey := edPrivateKey.Public().(ed25519.PublicKey)
+	require.Equal(t, decodeHex(t, "b5076a8474a832daee4dd5b4040983b6623b5f344aca57d4d6ee4baf3f259e6e"), []byte(edPublicKey))
+
+	publicKey, err := NewPublicKeyFromEd25519(edPublicKey)
+	require.NoError(t, err)
+	require.Equal(t, NewPublicKey(decodeHex(t, "f1814f0e8ff1043d8a44d25babff3cedcae6c22c3edaa48f857ae70de2baae50")), publicKey)
+
+	privateKey, err := NewPrivateKeyFromEd25519(edPrivateKey)
+	require.NoError(t, err)
+	require.Equal(t, decodeHex(t, "8052030376d47112be7f73ed7a019293dd12ad910b654455798b4667d73de166"), privateKey.raw[:])
+	require.Equal(t, publicKey, privateKey.Public())
+
+	back, err := publicKey.ToEd25519(edPublicKey[31] >> 7)
+	require.NoError(t, err)
+	require.Equal(t, edPublicKey, back)
+
+	for i := 0; i < 100; i++ {
+		edPublicKey, edPrivateKey, err := ed25519.GenerateKey(reader)
+		require.NoError(t, err)
+
+		publicKey, err := NewPublicKeyFromEd25519(edPublicKey)
+		require.NoError(t, err)
+		privateKey, err := NewPrivateKeyFromEd25519(edPrivateKey)
+		require.NoError(t, err)
+		require.Equal(t, publicKey, privateKey.Public())
+
+		back, err := publicKey.ToEd25519(edPublicKey[31] >> 7)
+		require.NoError(t, err)
+		require.Equal(t, edPublicKey, back)
+
+		negated, err := publicKey.ToEd25519(1 - edPublicKey[31]>>7)
+		require.NoError(t, err)
+		require.Equal(t, []byte(edPublicKey[:31]), []byte(negated[:31]))
+		require.NotEqual(t, edPublicKey[31], negated[31])
+	}
+}
+
+func TestEd25519ConversionErrors(t *testing.T) {
+	_, err := NewPublicKeyFromEd25519(make([]byte, 31))
+	require.Error(t, err)
+	_, err = NewPrivateKeyFromEd25519(make([]byte, 31))
+	require.Error(t, err)
+	_, err = NewPrivateKeyFromEd25519(make([]byte, ed25519.SeedSize))
+	require.Error(t, err)
+
+	for _, invalid := range []string{
+		// identity
+		"0100000000000000000000000000000000000000000000000000000000000000",
+		// point of order 8
+		"26e8958fc2b227b045c3f489f2ef98f0d5dfac05d3c63339b13802886d53fc05",
+		// not on the curve
+		"0200000000000000000000000000000000000000000000000000000000000000",
+		// non-canonical y
+		"edffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f",
+	} {
+		_, err = NewPublicKeyFromEd25519(decodeHex(t, invalid))
+		require.Error(t, err, invalid)
+	}
+
+	// a valid point plus a point of order 8 is outside the prime-order subgroup
+	P := new(edPoint).scalarBaseMult(NewScalar([]byte{5})[:])
+	T, ok := new(edPoint).setBytes(decodeHex(t, "26e8958fc2b227b045c3f489f2ef98f0d5dfac05d3c63339b13802886d53fc05"))
+	require.True(t, ok)
+	_, err = NewPublicKeyFromEd25519(P.add(P, T).bytes())
+	require.Error(t, err)
+
+	publicKey := GenerateKeyFrom(reader).Public()
+	_, err = publicKey.ToEd25519(2)
+	require.Error(t, err)
+
+	// u = -1 has no Edwards equivalent, and u = 0 maps to a point of small order
+	_, err = NewPublicKey(decodeHex(t, "ecffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f")).ToEd25519(0)
+	require.Error(t, err)
+	_, err = new(PublicKey).ToEd25519(0)
+	require.Error(t, err)
+}
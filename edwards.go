@@ -0,0 +1,256 @@
+package curve25519
+
+/* A point on the twisted Edwards curve  -x^2 + y^2 = 1 + d x^2 y^2  which is
+ * birationally equivalent to Curve25519, in extended coordinates:
+ *   x = X/Z,  y = Y/Z,  x y = T/Z
+ * The addition law is complete, so every operation below runs in the same
+ * time regardless of its inputs, except where noted. */
+type edPoint struct {
+	x, y, z, t FieldElement
+}
+
+/* d = -121665/121666 */
+var edD = new(FieldElement).Mul(
+	new(FieldElement).Negate(feFromInt(121665)),
+	new(FieldElement).Invert(feFromInt(121666)))
+
+var edD2 = new(FieldElement).Add(edD, edD)
+
+/* the standard base point, with y = 4/5 and positive x */
+var edBase = func() *edPoint {
+	encoded := make([]byte, 32)
+	encoded[0] = 0x58
+	for i := 1; i < 32; i++ {
+		encoded[i] = 0x66
+	}
+	p, _ := new(edPoint).setBytes(encoded)
+	return p
+}()
+
+func feFromInt(n int) *FieldElement {
+	f := new(FieldElement)
+	f.l.set(n)
+	return f
+}
+
+/* f = cond ? a : b  without branching, cond must be 0 or 1 */
+func (f *FieldElement) cselect(a, b *FieldElement, cond int) *FieldElement {
+	mask := -int64(cond)
+	for i := range f.l {
+		f.l[i] = b.l[i] ^ (mask & (a.l[i] ^ b.l[i]))
+	}
+	return f
+}
+
+func (p *edPoint) identity() *edPoint {
+	p.x.Zero()
+	p.y.One()
+	p.z.One()
+	p.t.Zero()
+	return p
+}
+
+func (p *edPoint) set(q *edPoint) *edPoint {
+	*p = *q
+	return p
+}
+
+/* Decode a point from its 32-byte encoding: y in little-endian with the sign
+ * of x in the top bit.  Non-canonical encodings of y are rejected. */
+func (p *edPoint) setBytes(b []byte) (*edPoint, bool) {
+	if len(b) != 32 {
+		return nil, false
+	}
+	y, _ := new(FieldElement).SetBytes(b)
+	yBytes := y.Bytes()
+	for i := 0; i < 31; i++ {
+		if yBytes[i] != b[i] {
+			return nil, false
+		}
+	}
+	if yBytes[31] != b[31]&0x7F {
+		return nil, false
+	}
+	return p.setY(y, int(b[31]>>7))
+}
+
+/* Recover x from y and its sign:  x^2 = (y^2 - 1) / (d y^2 + 1)
+ * WARNING: this function has data-dependent timing */
+func (p *edPoint) setY(y *FieldElement, sign int) (*edPoint, bool) {
+	one := new(FieldElement).One()
+	y2 := new(FieldElement).Square(y)
+	u := new(FieldElement).Sub(y2, one)
+	v := new(FieldElement).Mul(y2, edD)
+	v.Add(v, one)
+	x2 := new(FieldElement).Mul(u, v.Invert(v))
+	x, ok := new(FieldElement).Sqrt(x2)
+	if !ok {
+		return nil, false
+	}
+	if sign == 1 {
+		if x.Equal(new(FieldElement)) {
+			return nil, false
+		}
+		x.Negate(x)
+	}
+	p.x.Set(x)
+	p.y.Set(y)
+	p.z.One()
+	p.t.Mul(x, y)
+	return p, true
+}
+
+func (p *edPoint) bytes() []byte {
+	zInv := new(FieldElement).Invert(&p.z)
+	x := new(FieldElement).Mul(&p.x, zInv)
+	y := new(FieldElement).Mul(&p.y, zInv)
+	b := y.Bytes()
+	if x.IsNegative() {
+		b[31] |= 0x80
+	}
+	return b
+}
+
+/* p = q1 + q2, the unified addition formula from RFC 8032 */
+func (p *edPoint) add(q1, q2 *edPoint) *edPoint {
+	a := new(FieldElement).Sub(&q1.y, &q1.x)
+	t := new(FieldElement).Sub(&q2.y, &q2.x)
+	a.Mul(a, t)
+	b := new(FieldElement).Add(&q1.y, &q1.x)
+	t.Add(&q2.y, &q2.x)
+	b.Mul(b, t)
+	c := new(FieldElement).Mul(&q1.t, edD2)
+	c.Mul(c, &q2.t)
+	d := new(FieldElement).Add(&q1.z, &q1.z)
+	d.Mul(d, &q2.z)
+
+	e := new(FieldElement).Sub(b, a)
+	f := new(FieldElement).Sub(d, c)
+	g := new(FieldElement).Add(d, c)
+	h := new(FieldElement).Add(b, a)
+
+	p.x.Mul(e, f)
+	p.y.Mul(g, h)
+	p.t.Mul(e, h)
+	p.z.Mul(f, g)
+	return p
+}
+
+/* p = 2 q */
+func (p *edPoint) double(q *edPoint) *edPoint {
+	a := new(FieldElement).Square(&q.x)
+	b := new(FieldElement).Square(&q.y)
+	c := new(FieldElement).Square(&q.z)
+	c.Add(c, c)
+	h := new(FieldElement).Add(a, b)
+	e := new(FieldElement).Add(&q.x, &q.y)
+	e.Square(e)
+	e.Sub(h, e)
+	g := new(FieldElement).Sub(a, b)
+	f := new(FieldElement).Add(c, g)
+
+	p.x.Mul(e, f)
+	p.y.Mul(g, h)
+	p.t.Mul(e, h)
+	p.z.Mul(f, g)
+	return p
+}
+
+func (p *edPoint) negate(q *edPoint) *edPoint {
+	p.x.Negate(&q.x)
+	p.y.Set(&q.y)
+	p.z.Set(&q.z)
+	p.t.Negate(&q.t)
+	return p
+}
+
+/* p = cond ? a : b  without branching */
+func (p *edPoint) cselect(a, b *edPoint, cond int) *edPoint {
+	p.x.cselect(&a.x, &b.x, cond)
+	p.y.cselect(&a.y, &b.y, cond)
+	p.z.cselect(&a.z, &b.z, cond)
+	p.t.cselect(&a.t, &b.t, cond)
+	return p
+}
+
+/* p = k q  where k is 32 bytes little-endian, not necessarily reduced */
+func (p *edPoint) scalarMult(k []byte, q *edPoint) *edPoint {
+	base := new(edPoint).set(q)
+	acc := new(edPoint).identity()
+	sum := new(edPoint)
+	for i := 255; i >= 0; i-- {
+		acc.double(acc)
+		sum.add(acc, base)
+		acc.cselect(sum, acc, int(k[i>>3]>>uint(i&7))&1)
+	}
+	return p.set(acc)
+}
+
+func (p *edPoint) scalarBaseMult(k []byte) *edPoint {
+	return p.scalarMult(k, edBase)
+}
+
+/* p = 8 q */
+func (p *edPoint) mulByCofactor(q *edPoint) *edPoint {
+	p.double(q)
+	p.double(p)
+	return p.double(p)
+}
+
+/* WARNING: this function has data-dependent timing */
+func (p *edPoint) isIdentity() bool {
+	return p.x.Equal(new(FieldElement)) && p.y.Equal(&p.z)
+}
+
+func (p *edPoint) equal(q *edPoint) bool {
+	/* x1/z1 == x2/z2 and y1/z1 == y2/z2 */
+	a := new(FieldElement).Mul(&p.x, &q.z)
+	b := new(FieldElement).Mul(&q.x, &p.z)
+	c := new(FieldElement).Mul(&p.y, &q.z)
+	d := new(FieldElement).Mul(&q.y, &p.z)
+	return a.Equal(b) && c.Equal(d)
+}
+
+/* Ed25519 public key to Curve25519 public key, u = (1 + y) / (1 - y)
+ *   u  [out] Montgomery u-coordinate
+ *   A  [in]  Edwards point encoding
+ * Like libsodium, rejects points that are invalid, of small order or outside
+ * the prime-order subgroup.
+ * WARNING: this function has data-dependent timing */
+func edwardsToMontgomery(u, A []byte) bool {
+	P, ok := new(edPoint).setBytes(A)
+	if !ok {
+		return false
+	}
+	if new(edPoint).mulByCofactor(P).isIdentity() {
+		return false
+	}
+	if !new(edPoint).scalarMult(order, P).isIdentity() {
+		return false
+	}
+
+	one := new(FieldElement).One()
+	y, _ := new(FieldElement).SetBytes(A)
+	t := new(FieldElement).Sub(one, y)
+	t.Invert(t)
+	t.Mul(t, new(FieldElement).Add(one, y))
+	copy(u, t.Bytes())
+	return true
+}
+
+/* Curve25519 public key to Ed25519 public key, y = (u - 1) / (u + 1)
+ *   A     [out] Edwards point encoding
+ *   u     [in]  Montgomery u-coordinate
+ *   sign  [in]  sign of the Edwards x-coordinate, which u doesn't determine
+ * WARNING: this function has data-dependent timing */
+func montgomeryToEdwards(A, u []byte, sign int) bool {
+	P, ok := convertMont(u, sign)
+	if !ok {
+		return false
+	}
+	if new(edPoint).mulByCofactor(P).isIdentity() {
+		return false
+	}
+	copy(A, P.bytes())
+	return true
+}
@@ -0,0 +1,134 @@
+package curve25519
+
+import (
+	"crypto/subtle"
+	"errors"
+)
+
+// FieldElement is an element of GF(2^255-19).  The zero value is zero.
+//
+// Unlike long10, every method leaves the element in reduced form, so the
+// results can be fed into any other method or encoded directly.  All methods
+// accept aliased arguments.
+type FieldElement struct {
+	l long10
+}
+
+// Zero sets f = 0 and returns f.
+func (f *FieldElement) Zero() *FieldElement {
+	f.l.set(0)
+	return f
+}
+
+// One sets f = 1 and returns f.
+func (f *FieldElement) One() *FieldElement {
+	f.l.set(1)
+	return f
+}
+
+// Set sets f = x and returns f.
+func (f *FieldElement) Set(x *FieldElement) *FieldElement {
+	f.l.cpy(&x.l)
+	return f
+}
+
+// SetBytes sets f to the 32-byte little-endian encoding x and returns f.  As
+// in RFC 7748 the most significant bit is ignored, and values between
+// 2^255-19 and 2^255-1 are accepted and reduced.
+func (f *FieldElement) SetBytes(x []byte) (*FieldElement, error) {
+	if len(x) != 32 {
+		return nil, errors.New("curve25519: invalid field element length")
+	}
+	m := append(x[:0:0], x...)
+	m[31] &= 0x7F
+	f.l.unpack(m)
+	return f, nil
+}
+
+// Bytes returns the canonical 32-byte little-endian encoding of f.
+func (f *FieldElement) Bytes() []byte {
+	return f.l.pack(nil)
+}
+
+// Equal reports whether f and g represent the same element.
+func (f *FieldElement) Equal(g *FieldElement) bool {
+	return subtle.ConstantTimeCompare(f.Bytes(), g.Bytes()) == 1
+}
+
+// IsNegative reports whether the canonical encoding of f is odd.
+func (f *FieldElement) IsNegative() bool {
+	return f.Bytes()[0]&1 == 1
+}
+
+// Add sets f = x + y and returns f.
+func (f *FieldElement) Add(x, y *FieldElement) *FieldElement {
+	f.l.add(&x.l, &y.l)
+	f.l.normalize()
+	return f
+}
+
+// Sub sets f = x - y and returns f.
+func (f *FieldElement) Sub(x, y *FieldElement) *FieldElement {
+	f.l.sub(&x.l, &y.l)
+	f.l.normalize()
+	return f
+}
+
+// Negate sets f = -x and returns f.
+func (f *FieldElement) Negate(x *FieldElement) *FieldElement {
+	return f.Sub(new(FieldElement), x)
+}
+
+// Mul sets f = x * y and returns f.
+func (f *FieldElement) Mul(x, y *FieldElement) *FieldElement {
+	t := new(long10)
+	t.mul(&x.l, &y.l)
+	t.normalize()
+	f.l.cpy(t)
+	return f
+}
+
+// Square sets f = x * x and returns f.
+func (f *FieldElement) Square(x *FieldElement) *FieldElement {
+	t := new(long10)
+	t.sqr(&x.l)
+	t.normalize()
+	f.l.cpy(t)
+	return f
+}
+
+// Invert sets f = 1/x and returns f.  The inverse of zero is zero.
+func (f *FieldElement) Invert(x *FieldElement) *FieldElement {
+	t := new(long10)
+	t.recip(&x.l, false)
+	t.normalize()
+	f.l.cpy(t)
+	return f
+}
+
+// Sqrt sets f to the non-negative square root of x and returns f and true.
+// If x is not a square, f is set to zero and false is returned.
+func (f *FieldElement) Sqrt(x *FieldElement) (*FieldElement, bool) {
+	r := new(FieldElement)
+	r.l.sqrt(&x.l)
+	r.l.normalize()
+
+	check := new(FieldElement).Square(r)
+	if !check.Equal(x) {
+		f.Zero()
+		return f, false
+	}
+	if r.IsNegative() {
+		r.Negate(r)
+	}
+	return f.Set(r), true
+}
+
+/* Bring x into the form that pack handles correctly: one pass of mulSmall
+ * leaves the limbs reduced, but the top limb may still be negative (e.g. for
+ * 0 - 1), which pack mistakes for an overflow.  A second pass carries that
+ * into the lower limbs, after which every limb is non-negative. */
+func (x *long10) normalize() {
+	x.mulSmall(x, 1)
+	x.mulSmall(x, 1)
+}
@@ -0,0 +1,97 @@
+package curve25519
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var bigP = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(19))
+
+func bigToField(x *big.Int) []byte {
+	be := new(big.Int).Mod(x, bigP).Bytes()
+	le := make([]byte, 32)
+	for i := range be {
+		le[len(be)-1-i] = be[i]
+	}
+	return le
+}
+
+func randomFieldElement(t *testing.T) (*FieldElement, *big.Int) {
+	b := randomBytes(32)
+	f, err := new(FieldElement).SetBytes(b)
+	require.NoError(t, err)
+	b[31] &= 0x7F
+	return f, leToBig(b)
+}
+
+func TestFieldElement(t *testing.T) {
+	for i := 0; i < 10000; i++ {
+		x, bx := randomFieldElement(t)
+		y, by := randomFieldElement(t)
+
+		require.Equal(t, bigToField(bx), x.Bytes())
+		require.Equal(t, bigToField(new(big.Int).Add(bx, by)), new(FieldElement).Add(x, y).Bytes())
+		require.Equal(t, bigToField(new(big.Int).Sub(bx, by)), new(FieldElement).Sub(x, y).Bytes())
+		require.Equal(t, bigToField(new(big.Int).Neg(bx)), new(FieldElement).Negate(x).Bytes())
+		require.Equal(t, bigToField(new(big.Int).Mul(bx, by)), new(FieldElement).Mul(x, y).Bytes())
+		require.Equal(t, bigToField(new(big.Int).Mul(bx, bx)), new(FieldElement).Square(x).Bytes())
+		require.Equal(t, bigToField(new(big.Int).ModInverse(bx, bigP)), new(FieldElement).Invert(x).Bytes())
+
+		root, ok := new(FieldElement).Sqrt(x)
+		expected := new(big.Int).ModSqrt(new(big.Int).Mod(bx, bigP), bigP)
+		require.Equal(t, expected != nil, ok)
+		if ok {
+			require.False(t, root.IsNegative())
+			require.Equal(t, bigToField(bx), new(FieldElement).Square(root).Bytes())
+		} else {
+			require.Equal(t, new(FieldElement).Bytes(), root.Bytes())
+		}
+
+		require.Equal(t, bx.Bit(0) == 1, x.IsNegative() != (bx.Cmp(bigP) >= 0))
+		require.True(t, x.Equal(new(FieldElement).Add(x, new(FieldElement))))
+		require.False(t, x.Equal(new(FieldElement).Add(x, new(FieldElement).One())))
+
+		/* results must stay reduced through long chains of additions */
+		z := new(FieldElement).Set(x)
+		bz := new(big.Int).Set(bx)
+		for j := 0; j < 10; j++ {
+			z.Add(z, z)
+			z.Sub(z, y)
+			bz.Sub(bz.Add(bz, bz), by)
+		}
+		require.Equal(t, bigToField(bz), z.Bytes())
+		require.Equal(t, bigToField(new(big.Int).Mul(bz, bz)), z.Square(z).Bytes())
+	}
+}
+
+func TestFieldElementEdgeCases(t *testing.T) {
+	_, err := new(FieldElement).SetBytes(make([]byte, 31))
+	require.Error(t, err)
+
+	pBytes := bigToField(new(big.Int).Sub(bigP, big.NewInt(1)))
+	pBytes[0]++
+	x, err := new(FieldElement).SetBytes(pBytes)
+	require.NoError(t, err)
+	require.Equal(t, make([]byte, 32), x.Bytes())
+	require.True(t, x.Equal(new(FieldElement)))
+
+	high := make([]byte, 32)
+	high[0] = 1
+	high[31] = 0x80
+	x, err = new(FieldElement).SetBytes(high)
+	require.NoError(t, err)
+	require.True(t, x.Equal(new(FieldElement).One()))
+
+	require.Equal(t, make([]byte, 32), new(FieldElement).Invert(new(FieldElement)).Bytes())
+	root, ok := new(FieldElement).Sqrt(new(FieldElement))
+	require.True(t, ok)
+	require.Equal(t, make([]byte, 32), root.Bytes())
+
+	minusOne := new(FieldElement).Negate(new(FieldElement).One())
+	require.Equal(t, bigToField(big.NewInt(-1)), minusOne.Bytes())
+	root, ok = new(FieldElement).Sqrt(minusOne)
+	require.True(t, ok)
+	require.True(t, minusOne.Equal(new(FieldElement).Square(root)))
+}
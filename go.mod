@@ -1,8 +1,9 @@
 module github.com/moonfruit/go-curve25519
 
-go 1.12
+go 1.13
 
 require (
 	github.com/stretchr/testify v1.3.0
-	golang.org/x/crypto v0.0.0-20190701094942-4def268fd1a4
+	golang.org/x/crypto v0.0.0-20210506145944-38f3c27a63bf
+	golang.org/x/sys v0.0.0-20210615035016-665e8c7367d1 // indirect
 )
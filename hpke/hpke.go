@@ -0,0 +1,337 @@
+// Package hpke implements Hybrid Public Key Encryption (RFC 9180) with
+// DHKEM(X25519, HKDF-SHA256), in the base, PSK, auth and auth-PSK modes.
+//
+// A sender sets up a Sender context to a recipient's public key, which gives
+// it the encapsulated key enc to send along with its ciphertexts.  The
+// recipient sets up the matching Receiver context from enc and its private
+// key.  Both can derive secrets with Export.
+package hpke
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"io"
+	"math"
+
+	curve25519 "github.com/moonfruit/go-curve25519"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+// KDF is the identifier of a key derivation function.
+type KDF uint16
+
+const (
+	HKDFSHA256 KDF = 0x0001
+	HKDFSHA384 KDF = 0x0002
+	HKDFSHA512 KDF = 0x0003
+)
+
+// AEAD is the identifier of an authenticated encryption algorithm.
+type AEAD uint16
+
+const (
+	AES128GCM        AEAD = 0x0001
+	AES256GCM        AEAD = 0x0002
+	ChaCha20Poly1305 AEAD = 0x0003
+	// ExportOnly makes contexts that can only Export, not Seal or Open.
+	ExportOnly AEAD = 0xFFFF
+)
+
+// Mode is the mode of a context.
+type Mode uint8
+
+const (
+	ModeBase    Mode = 0x00
+	ModePSK     Mode = 0x01
+	ModeAuth    Mode = 0x02
+	ModeAuthPSK Mode = 0x03
+)
+
+var (
+	// ErrOpen is returned when a ciphertext can't be decrypted.
+	ErrOpen = errors.New("hpke: message authentication failed")
+	// ErrExportOnly is returned when sealing or opening with an ExportOnly
+	// context.
+	ErrExportOnly = errors.New("hpke: context is export-only")
+	// ErrMessageLimit is returned when a context has used up its nonces.
+	ErrMessageLimit = errors.New("hpke: message limit reached")
+)
+
+// Suite is a combination of a KDF and an AEAD, with DHKEM(X25519,
+// HKDF-SHA256) as the KEM.
+type Suite struct {
+	kdf  kdf
+	aead AEAD
+	id   []byte
+}
+
+// NewSuite returns the suite of kdfID and aeadID.
+func NewSuite(kdfID KDF, aeadID AEAD) (suite *Suite, err error) {
+	suite = &Suite{aead: aeadID}
+	switch kdfID {
+	case HKDFSHA256:
+		suite.kdf = kdf{sha256.New}
+	case HKDFSHA384:
+		suite.kdf = kdf{sha512.New384}
+	case HKDFSHA512:
+		suite.kdf = kdf{sha512.New}
+	default:
+		return nil, errors.New("hpke: unsupported KDF")
+	}
+	switch aeadID {
+	case AES128GCM, AES256GCM, ChaCha20Poly1305, ExportOnly:
+	default:
+		return nil, errors.New("hpke: unsupported AEAD")
+	}
+	suite.id = []byte{'H', 'P', 'K', 'E', byte(KEM >> 8), byte(KEM),
+		byte(kdfID >> 8), byte(kdfID), byte(aeadID >> 8), byte(aeadID)}
+	return
+}
+
+// SetupBaseSender sets up a context to send to pkR, with an ephemeral key
+// generated from reader.
+func (s *Suite) SetupBaseSender(reader io.Reader, pkR *curve25519.PublicKey, info []byte) (enc []byte, sender *Sender, err error) {
+	return s.setupSender(reader, ModeBase, pkR, info, nil, nil, nil)
+}
+
+// SetupBaseReceiver sets up a context to receive from the sender of enc.
+func (s *Suite) SetupBaseReceiver(enc []byte, skR *curve25519.PrivateKey, info []byte) (receiver *Receiver, err error) {
+	return s.setupReceiver(ModeBase, enc, skR, info, nil, nil, nil)
+}
+
+// SetupPSKSender is SetupBaseSender which also authenticates the sender by
+// its knowledge of the pre-shared key psk, identified by pskID.
+func (s *Suite) SetupPSKSender(reader io.Reader, pkR *curve25519.PublicKey, info, psk, pskID []byte) (enc []byte, sender *Sender, err error) {
+	return s.setupSender(reader, ModePSK, pkR, info, psk, pskID, nil)
+}
+
+// SetupPSKReceiver is SetupBaseReceiver for SetupPSKSender.
+func (s *Suite) SetupPSKReceiver(enc []byte, skR *curve25519.PrivateKey, info, psk, pskID []byte) (receiver *Receiver, err error) {
+	return s.setupReceiver(ModePSK, enc, skR, info, psk, pskID, nil)
+}
+
+// SetupAuthSender is SetupBaseSender which also authenticates the sender by
+// its private key skS.
+func (s *Suite) SetupAuthSender(reader io.Reader, pkR *curve25519.PublicKey, info []byte, skS *curve25519.PrivateKey) (enc []byte, sender *Sender, err error) {
+	return s.setupSender(reader, ModeAuth, pkR, info, nil, nil, skS)
+}
+
+// SetupAuthReceiver is SetupBaseReceiver for SetupAuthSender, where pkS is
+// the sender's public key.
+func (s *Suite) SetupAuthReceiver(enc []byte, skR *curve25519.PrivateKey, info []byte, pkS *curve25519.PublicKey) (receiver *Receiver, err error) {
+	return s.setupReceiver(ModeAuth, enc, skR, info, nil, nil, pkS)
+}
+
+// SetupAuthPSKSender combines SetupAuthSender and SetupPSKSender.
+func (s *Suite) SetupAuthPSKSender(reader io.Reader, pkR *curve25519.PublicKey, info, psk, pskID []byte, skS *curve25519.PrivateKey) (enc []byte, sender *Sender, err error) {
+	return s.setupSender(reader, ModeAuthPSK, pkR, info, psk, pskID, skS)
+}
+
+// SetupAuthPSKReceiver is SetupBaseReceiver for SetupAuthPSKSender.
+func (s *Suite) SetupAuthPSKReceiver(enc []byte, skR *curve25519.PrivateKey, info, psk, pskID []byte, pkS *curve25519.PublicKey) (receiver *Receiver, err error) {
+	return s.setupReceiver(ModeAuthPSK, enc, skR, info, psk, pskID, pkS)
+}
+
+func (s *Suite) setupSender(reader io.Reader, mode Mode, pkR *curve25519.PublicKey, info, psk, pskID []byte, skS *curve25519.PrivateKey) (enc []byte, sender *Sender, err error) {
+	if err = verifyPSKInputs(mode, psk, pskID); err != nil {
+		return nil, nil, err
+	}
+	sharedSecret, enc, err := encap(reader, pkR, skS)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx, err := s.keySchedule(mode, sharedSecret, info, psk, pskID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return enc, &Sender{ctx}, nil
+}
+
+func (s *Suite) setupReceiver(mode Mode, enc []byte, skR *curve25519.PrivateKey, info, psk, pskID []byte, pkS *curve25519.PublicKey) (receiver *Receiver, err error) {
+	if err = verifyPSKInputs(mode, psk, pskID); err != nil {
+		return nil, err
+	}
+	sharedSecret, err := decap(enc, skR, pkS)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err := s.keySchedule(mode, sharedSecret, info, psk, pskID)
+	if err != nil {
+		return nil, err
+	}
+	return &Receiver{ctx}, nil
+}
+
+func verifyPSKInputs(mode Mode, psk, pskID []byte) error {
+	if (len(psk) == 0) != (len(pskID) == 0) {
+		return errors.New("hpke: psk and psk_id must be given together")
+	}
+	withPSK := mode == ModePSK || mode == ModeAuthPSK
+	if withPSK && len(psk) == 0 {
+		return errors.New("hpke: missing psk")
+	}
+	if !withPSK && len(psk) != 0 {
+		return errors.New("hpke: psk given in a mode without psk")
+	}
+	if len(psk) != 0 && len(psk) < 32 {
+		return errors.New("hpke: psk must have at least 32 bytes")
+	}
+	return nil
+}
+
+func (s *Suite) keySchedule(mode Mode, sharedSecret, info, psk, pskID []byte) (ctx *context, err error) {
+	pskIDHash := s.kdf.labeledExtract(s.id, nil, "psk_id_hash", pskID)
+	infoHash := s.kdf.labeledExtract(s.id, nil, "info_hash", info)
+	keyScheduleContext := append([]byte{byte(mode)}, pskIDHash...)
+	keyScheduleContext = append(keyScheduleContext, infoHash...)
+
+	secret := s.kdf.labeledExtract(s.id, sharedSecret, "secret", psk)
+	ctx = &context{
+		suite:          s,
+		exporterSecret: s.kdf.labeledExpand(s.id, secret, "exp", keyScheduleContext, s.kdf.size()),
+	}
+	if s.aead == ExportOnly {
+		return
+	}
+	var keySize int
+	switch s.aead {
+	case AES128GCM:
+		keySize = 16
+	case AES256GCM, ChaCha20Poly1305:
+		keySize = 32
+	}
+	key := s.kdf.labeledExpand(s.id, secret, "key", keyScheduleContext, keySize)
+	if s.aead == ChaCha20Poly1305 {
+		ctx.aead, err = chacha20poly1305.New(key)
+	} else {
+		var block cipher.Block
+		if block, err = aes.NewCipher(key); err == nil {
+			ctx.aead, err = cipher.NewGCM(block)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	ctx.baseNonce = s.kdf.labeledExpand(s.id, secret, "base_nonce", keyScheduleContext, ctx.aead.NonceSize())
+	return
+}
+
+type context struct {
+	suite          *Suite
+	aead           cipher.AEAD
+	baseNonce      []byte
+	seq            uint64
+	exporterSecret []byte
+}
+
+// Sender is the context of the sending side.  It must not be used
+// concurrently.
+type Sender struct {
+	*context
+}
+
+// Receiver is the context of the receiving side.  It must not be used
+// concurrently.
+type Receiver struct {
+	*context
+}
+
+// Seal encrypts and authenticates plaintext, and authenticates aad.  The
+// receiver must open the ciphertexts in the order they were sealed.
+func (s *Sender) Seal(aad, plaintext []byte) (ciphertext []byte, err error) {
+	if err = s.checkNonce(); err != nil {
+		return nil, err
+	}
+	ciphertext = s.aead.Seal(nil, s.nonce(), plaintext, aad)
+	s.seq++
+	return
+}
+
+// Open decrypts the next ciphertext sealed by the sender, with the same aad.
+func (r *Receiver) Open(aad, ciphertext []byte) (plaintext []byte, err error) {
+	if err = r.checkNonce(); err != nil {
+		return nil, err
+	}
+	if plaintext, err = r.aead.Open(nil, r.nonce(), ciphertext, aad); err != nil {
+		return nil, ErrOpen
+	}
+	r.seq++
+	return
+}
+
+// Export derives a secret of length bytes, bound to exporterContext, which
+// the receiver derives as well.
+func (s *Sender) Export(exporterContext []byte, length int) ([]byte, error) {
+	return s.export(exporterContext, length)
+}
+
+// Export derives a secret of length bytes, bound to exporterContext, which
+// the sender derives as well.
+func (r *Receiver) Export(exporterContext []byte, length int) ([]byte, error) {
+	return r.export(exporterContext, length)
+}
+
+func (c *context) export(exporterContext []byte, length int) ([]byte, error) {
+	if length < 0 || length > 255*c.suite.kdf.size() {
+		return nil, errors.New("hpke: invalid export length")
+	}
+	return c.suite.kdf.labeledExpand(c.suite.id, c.exporterSecret, "sec", exporterContext, length), nil
+}
+
+func (c *context) checkNonce() error {
+	if c.aead == nil {
+		return ErrExportOnly
+	}
+	/* the nonces are at least 12 bytes, so the 64-bit counter is the limit */
+	if c.seq == math.MaxUint64 {
+		return ErrMessageLimit
+	}
+	return nil
+}
+
+/* base_nonce XOR I2OSP(seq, Nn) */
+func (c *context) nonce() []byte {
+	nonce := make([]byte, len(c.baseNonce))
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], c.seq)
+	for i := range nonce {
+		nonce[i] ^= c.baseNonce[i]
+	}
+	return nonce
+}
+
+type kdf struct {
+	hash func() hash.Hash
+}
+
+func (k kdf) size() int {
+	return k.hash().Size()
+}
+
+func (k kdf) labeledExtract(suiteID, salt []byte, label string, ikm []byte) []byte {
+	labeledIKM := make([]byte, 0, 7+len(suiteID)+len(label)+len(ikm))
+	labeledIKM = append(labeledIKM, "HPKE-v1"...)
+	labeledIKM = append(labeledIKM, suiteID...)
+	labeledIKM = append(labeledIKM, label...)
+	labeledIKM = append(labeledIKM, ikm...)
+	return hkdf.Extract(k.hash, labeledIKM, salt)
+}
+
+func (k kdf) labeledExpand(suiteID, prk []byte, label string, info []byte, length int) []byte {
+	labeledInfo := make([]byte, 0, 9+len(suiteID)+len(label)+len(info))
+	labeledInfo = append(labeledInfo, i2osp2(length)...)
+	labeledInfo = append(labeledInfo, "HPKE-v1"...)
+	labeledInfo = append(labeledInfo, suiteID...)
+	labeledInfo = append(labeledInfo, label...)
+	labeledInfo = append(labeledInfo, info...)
+	out := make([]byte, length)
+	if _, err := io.ReadFull(hkdf.Expand(k.hash, prk, labeledInfo), out); err != nil {
+		panic(err) /* length is checked against the HKDF limit by the callers */
+	}
+	return out
+}
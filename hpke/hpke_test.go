@@ -0,0 +1,245 @@
+package hpke
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"testing"
+	"time"
+
+	curve25519 "github.com/moonfruit/go-curve25519"
+	"github.com/stretchr/testify/require"
+)
+
+var reader = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+type hexBytes []byte
+
+func (h *hexBytes) UnmarshalText(text []byte) (err error) {
+	*h, err = hex.DecodeString(string(text))
+	return
+}
+
+type vector struct {
+	Mode               Mode     `json:"mode"`
+	KEM                uint16   `json:"kem_id"`
+	KDF                KDF      `json:"kdf_id"`
+	AEAD               AEAD     `json:"aead_id"`
+	Info               hexBytes `json:"info"`
+	IKMR               hexBytes `json:"ikmR"`
+	IKMS               hexBytes `json:"ikmS"`
+	IKME               hexBytes `json:"ikmE"`
+	SKRm               hexBytes `json:"skRm"`
+	SKSm               hexBytes `json:"skSm"`
+	SKEm               hexBytes `json:"skEm"`
+	PSK                hexBytes `json:"psk"`
+	PSKID              hexBytes `json:"psk_id"`
+	PKRm               hexBytes `json:"pkRm"`
+	PKSm               hexBytes `json:"pkSm"`
+	PKEm               hexBytes `json:"pkEm"`
+	Enc                hexBytes `json:"enc"`
+	SharedSecret       hexBytes `json:"shared_secret"`
+	KeyScheduleContext hexBytes `json:"key_schedule_context"`
+	Secret             hexBytes `json:"secret"`
+	Key                hexBytes `json:"key"`
+	BaseNonce          hexBytes `json:"base_nonce"`
+	ExporterSecret     hexBytes `json:"exporter_secret"`
+	Encryptions        []struct {
+		AAD        hexBytes `json:"aad"`
+		Ciphertext hexBytes `json:"ct"`
+		Nonce      hexBytes `json:"nonce"`
+		Plaintext  hexBytes `json:"pt"`
+	} `json:"encryptions"`
+	Exports []struct {
+		Context hexBytes `json:"exporter_context"`
+		Length  int      `json:"L"`
+		Value   hexBytes `json:"exported_value"`
+	} `json:"exports"`
+}
+
+func setup(t *testing.T, suite *Suite, v *vector) (*Sender, *Receiver) {
+	skR, err := DeriveKeyPair(v.IKMR)
+	require.NoError(t, err)
+	require.True(t, curve25519.NewPrivateKey(v.SKRm).Equal(skR))
+	require.Equal(t, []byte(v.PKRm), skR.Public()[:])
+	var skS *curve25519.PrivateKey
+	if v.IKMS != nil {
+		skS, err = DeriveKeyPair(v.IKMS)
+		require.NoError(t, err)
+		require.Equal(t, []byte(v.PKSm), skS.Public()[:])
+	}
+
+	ikmE := bytes.NewReader(v.IKME)
+	var enc []byte
+	var sender *Sender
+	var receiver *Receiver
+	switch v.Mode {
+	case ModeBase:
+		enc, sender, err = suite.SetupBaseSender(ikmE, skR.Public(), v.Info)
+		require.NoError(t, err)
+		receiver, err = suite.SetupBaseReceiver(enc, skR, v.Info)
+	case ModePSK:
+		enc, sender, err = suite.SetupPSKSender(ikmE, skR.Public(), v.Info, v.PSK, v.PSKID)
+		require.NoError(t, err)
+		receiver, err = suite.SetupPSKReceiver(enc, skR, v.Info, v.PSK, v.PSKID)
+	case ModeAuth:
+		enc, sender, err = suite.SetupAuthSender(ikmE, skR.Public(), v.Info, skS)
+		require.NoError(t, err)
+		receiver, err = suite.SetupAuthReceiver(enc, skR, v.Info, skS.Public())
+	case ModeAuthPSK:
+		enc, sender, err = suite.SetupAuthPSKSender(ikmE, skR.Public(), v.Info, v.PSK, v.PSKID, skS)
+		require.NoError(t, err)
+		receiver, err = suite.SetupAuthPSKReceiver(enc, skR, v.Info, v.PSK, v.PSKID, skS.Public())
+	}
+	require.NoError(t, err)
+	require.Equal(t, []byte(v.Enc), enc)
+	require.Equal(t, []byte(v.PKEm), enc)
+	return sender, receiver
+}
+
+func TestVectors(t *testing.T) {
+	// the RFC 9180 test vectors for DHKEM(X25519, HKDF-SHA256), with only
+	// the first and last few encryptions of each
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "test-vectors.json"))
+	require.NoError(t, err)
+	var vectors []vector
+	require.NoError(t, json.Unmarshal(data, &vectors))
+	require.Len(t, vectors, 32)
+
+	for _, v := range vectors {
+		require.Equal(t, KEM, v.KEM)
+		suite, err := NewSuite(v.KDF, v.AEAD)
+		require.NoError(t, err)
+		sender, receiver := setup(t, suite, &v)
+		require.Equal(t, []byte(v.ExporterSecret), sender.exporterSecret)
+		if v.AEAD != ExportOnly {
+			require.Equal(t, []byte(v.BaseNonce), sender.baseNonce)
+		}
+
+		for _, encryption := range v.Encryptions {
+			seq := binary.BigEndian.Uint64(encryption.Nonce[4:]) ^ binary.BigEndian.Uint64(v.BaseNonce[4:])
+			for sender.seq < seq {
+				ciphertext, err := sender.Seal(nil, nil)
+				require.NoError(t, err)
+				_, err = receiver.Open(nil, ciphertext)
+				require.NoError(t, err)
+			}
+			require.Equal(t, []byte(encryption.Nonce), sender.nonce())
+			ciphertext, err := sender.Seal(encryption.AAD, encryption.Plaintext)
+			require.NoError(t, err)
+			require.Equal(t, []byte(encryption.Ciphertext), ciphertext)
+			plaintext, err := receiver.Open(encryption.AAD, ciphertext)
+			require.NoError(t, err)
+			require.Equal(t, []byte(encryption.Plaintext), plaintext)
+		}
+		if v.AEAD == ExportOnly {
+			_, err = sender.Seal(nil, nil)
+			require.Equal(t, ErrExportOnly, err)
+			_, err = receiver.Open(nil, nil)
+			require.Equal(t, ErrExportOnly, err)
+		}
+
+		for _, export := range v.Exports {
+			value, err := sender.Export(export.Context, export.Length)
+			require.NoError(t, err)
+			require.Equal(t, []byte(export.Value), value)
+			value, err = receiver.Export(export.Context, export.Length)
+			require.NoError(t, err)
+			require.Equal(t, []byte(export.Value), value)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	skR := curve25519.GenerateKeyFrom(reader)
+	skS := curve25519.GenerateKeyFrom(reader)
+	psk := make([]byte, 32)
+	reader.Read(psk)
+	pskID := []byte("psk id")
+	info := []byte("info")
+
+	for _, aead := range []AEAD{AES128GCM, AES256GCM, ChaCha20Poly1305} {
+		suite, err := NewSuite(HKDFSHA384, aead)
+		require.NoError(t, err)
+
+		enc, sender, err := suite.SetupAuthPSKSender(reader, skR.Public(), info, psk, pskID, skS)
+		require.NoError(t, err)
+		receiver, err := suite.SetupAuthPSKReceiver(enc, skR, info, psk, pskID, skS.Public())
+		require.NoError(t, err)
+
+		first, err := sender.Seal([]byte("aad"), []byte("first"))
+		require.NoError(t, err)
+		second, err := sender.Seal([]byte("aad"), []byte("second"))
+		require.NoError(t, err)
+		// out of order
+		_, err = receiver.Open([]byte("aad"), second)
+		require.Equal(t, ErrOpen, err)
+		_, err = receiver.Open([]byte("other"), first)
+		require.Equal(t, ErrOpen, err)
+		plaintext, err := receiver.Open([]byte("aad"), first)
+		require.NoError(t, err)
+		require.Equal(t, []byte("first"), plaintext)
+		plaintext, err = receiver.Open([]byte("aad"), second)
+		require.NoError(t, err)
+		require.Equal(t, []byte("second"), plaintext)
+
+		// the wrong sender key or psk
+		receiver, err = suite.SetupAuthPSKReceiver(enc, skR, info, psk, pskID, curve25519.GenerateKeyFrom(reader).Public())
+		require.NoError(t, err)
+		_, err = receiver.Open([]byte("aad"), first)
+		require.Equal(t, ErrOpen, err)
+		otherPSK := append([]byte{}, psk...)
+		otherPSK[0] ^= 1
+		receiver, err = suite.SetupAuthPSKReceiver(enc, skR, info, otherPSK, pskID, skS.Public())
+		require.NoError(t, err)
+		_, err = receiver.Open([]byte("aad"), first)
+		require.Equal(t, ErrOpen, err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	_, err := NewSuite(0x0004, AES128GCM)
+	require.Error(t, err)
+	_, err = NewSuite(HKDFSHA256, 0x0004)
+	require.Error(t, err)
+
+	suite, err := NewSuite(HKDFSHA256, ChaCha20Poly1305)
+	require.NoError(t, err)
+	skR := curve25519.GenerateKeyFrom(reader)
+	psk := make([]byte, 32)
+
+	_, _, err = suite.SetupPSKSender(reader, skR.Public(), nil, psk, nil)
+	require.Error(t, err)
+	_, _, err = suite.SetupPSKSender(reader, skR.Public(), nil, nil, nil)
+	require.Error(t, err)
+	_, _, err = suite.SetupPSKSender(reader, skR.Public(), nil, psk[:16], []byte("id"))
+	require.Error(t, err)
+	_, err = suite.SetupBaseReceiver(make([]byte, 31), skR, nil)
+	require.Error(t, err)
+
+	// an all-zero DH output from a low-order key
+	_, _, err = suite.SetupBaseSender(reader, new(curve25519.PublicKey), nil)
+	require.Equal(t, curve25519.ErrLowOrderPoint, err)
+	_, err = suite.SetupBaseReceiver(make([]byte, 32), skR, nil)
+	require.Equal(t, curve25519.ErrLowOrderPoint, err)
+
+	enc, sender, err := suite.SetupBaseSender(reader, skR.Public(), nil)
+	require.NoError(t, err)
+	receiver, err := suite.SetupBaseReceiver(enc, skR, nil)
+	require.NoError(t, err)
+	_, err = sender.Export(nil, 255*32+1)
+	require.Error(t, err)
+	sender.seq = 1<<64 - 1
+	_, err = sender.Seal(nil, nil)
+	require.Equal(t, ErrMessageLimit, err)
+	receiver.seq = 1<<64 - 1
+	_, err = receiver.Open(nil, nil)
+	require.Equal(t, ErrMessageLimit, err)
+
+	_, err = DeriveKeyPair(make([]byte, 31))
+	require.Error(t, err)
+}
@@ -0,0 +1,102 @@
+package hpke
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	curve25519 "github.com/moonfruit/go-curve25519"
+)
+
+// KEM is the identifier of DHKEM(X25519, HKDF-SHA256), the only KEM
+// supported by this package.
+const KEM uint16 = 0x0020
+
+const (
+	/* Nsecret, Nenc and Nsk of the KEM */
+	kemSecretSize     = 32
+	kemEncSize        = 32
+	kemPrivateKeySize = 32
+)
+
+var kemSuiteID = []byte{'K', 'E', 'M', byte(KEM >> 8), byte(KEM)}
+
+var kemKDF = kdf{sha256.New}
+
+// DeriveKeyPair deterministically derives a key pair from ikm, which should
+// have at least 32 bytes of entropy, as DeriveKeyPair of RFC 9180.
+func DeriveKeyPair(ikm []byte) (sk *curve25519.PrivateKey, err error) {
+	if len(ikm) < kemPrivateKeySize {
+		return nil, errors.New("hpke: input keying material is too short")
+	}
+	prk := kemKDF.labeledExtract(kemSuiteID, nil, "dkp_prk", ikm)
+	return curve25519.NewPrivateKey(kemKDF.labeledExpand(kemSuiteID, prk, "sk", nil, kemPrivateKeySize)), nil
+}
+
+/* generates an ephemeral key pair from kemPrivateKeySize random bytes, like
+ * GenerateKeyPair, so that the reader fully determines it */
+func generateKeyPair(reader io.Reader) (sk *curve25519.PrivateKey, err error) {
+	ikm := make([]byte, kemPrivateKeySize)
+	if _, err = io.ReadFull(reader, ikm); err != nil {
+		return nil, err
+	}
+	return DeriveKeyPair(ikm)
+}
+
+/* Encap and, when skS isn't nil, AuthEncap */
+func encap(reader io.Reader, pkR *curve25519.PublicKey, skS *curve25519.PrivateKey) (sharedSecret, enc []byte, err error) {
+	skE, err := generateKeyPair(reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	dh, err := skE.SharedSecretChecked(pkR)
+	if err != nil {
+		return nil, nil, err
+	}
+	enc = skE.Public()[:]
+	kemContext := append(append([]byte{}, enc...), pkR[:]...)
+	if skS != nil {
+		dhS, err := skS.SharedSecretChecked(pkR)
+		if err != nil {
+			return nil, nil, err
+		}
+		dh = append(dh, dhS...)
+		kemContext = append(kemContext, skS.Public()[:]...)
+	}
+	return extractAndExpand(dh, kemContext), enc, nil
+}
+
+/* Decap and, when pkS isn't nil, AuthDecap */
+func decap(enc []byte, skR *curve25519.PrivateKey, pkS *curve25519.PublicKey) (sharedSecret []byte, err error) {
+	if len(enc) != kemEncSize {
+		return nil, errors.New("hpke: invalid encapsulated key length")
+	}
+	pkE := curve25519.NewPublicKey(enc)
+	dh, err := skR.SharedSecretChecked(pkE)
+	if err != nil {
+		return nil, err
+	}
+	kemContext := append(append([]byte{}, enc...), skR.Public()[:]...)
+	if pkS != nil {
+		dhS, err := skR.SharedSecretChecked(pkS)
+		if err != nil {
+			return nil, err
+		}
+		dh = append(dh, dhS...)
+		kemContext = append(kemContext, pkS[:]...)
+	}
+	return extractAndExpand(dh, kemContext), nil
+}
+
+func extractAndExpand(dh, kemContext []byte) []byte {
+	prk := kemKDF.labeledExtract(kemSuiteID, nil, "eae_prk", dh)
+	return kemKDF.labeledExpand(kemSuiteID, prk, "shared_secret", kemContext, kemSecretSize)
+}
+
+/* I2OSP(n, 2) */
+func i2osp2(n int) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, uint16(n))
+	return b
+}
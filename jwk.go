@@ -0,0 +1,116 @@
+package curve25519
+
+import (
+	"crypto"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+/* JSON Web Key of an octet key pair, from RFC 8037 */
+type jwk struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	D   string `json:"d,omitempty"`
+}
+
+var jwkEncoding = base64.RawURLEncoding.Strict()
+
+// MarshalJWK returns pk as an RFC 8037 JSON Web Key, i.e.
+// {"kty":"OKP","crv":"X25519","x":...}.
+func (pk *PublicKey) MarshalJWK() ([]byte, error) {
+	return json.Marshal(jwk{Kty: "OKP", Crv: "X25519", X: jwkEncoding.EncodeToString(pk[:])})
+}
+
+// MarshalJWK returns sk as an RFC 8037 JSON Web Key, including both the
+// private key "d" and its public key "x".  "d" is the clamped key, which
+// differs from the "d" that an unclamped key was parsed from.
+func (sk *PrivateKey) MarshalJWK() ([]byte, error) {
+	return json.Marshal(jwk{
+		Kty: "OKP",
+		Crv: "X25519",
+		X:   jwkEncoding.EncodeToString(sk.Public()[:]),
+		D:   jwkEncoding.EncodeToString(sk.raw[:]),
+	})
+}
+
+// ParseJWKPublicKey parses the public key of an RFC 8037 X25519 JSON Web
+// Key.  Any private key in it is ignored.
+func ParseJWKPublicKey(data []byte) (pk *PublicKey, err error) {
+	key, err := parseJWK(data)
+	if err != nil {
+		return nil, err
+	}
+	return parseJWKPublicKey(key)
+}
+
+// ParseJWKPrivateKey parses the private key of an RFC 8037 X25519 JSON Web
+// Key, clamping it as NewPrivateKey does, so that MarshalJWK may give another
+// "d".  The public key "x", if present, must match it.
+func ParseJWKPrivateKey(data []byte) (sk *PrivateKey, err error) {
+	key, err := parseJWK(data)
+	if err != nil {
+		return nil, err
+	}
+	if key.D == "" {
+		return nil, errors.New("curve25519: JWK has no private key")
+	}
+	d, err := jwkEncoding.DecodeString(key.D)
+	if err != nil {
+		return nil, fmt.Errorf("curve25519: invalid JWK private key: %w", err)
+	}
+	if sk, err = ParsePrivateKey(d); err != nil {
+		return nil, err
+	}
+	if key.X != "" {
+		pk, err := parseJWKPublicKey(key)
+		if err != nil {
+			return nil, err
+		}
+		if subtle.ConstantTimeCompare(pk[:], sk.Public()[:]) != 1 {
+			return nil, errors.New("curve25519: JWK public key doesn't match its private key")
+		}
+	}
+	return
+}
+
+// JWKThumbprint returns the RFC 7638 thumbprint of pk, i.e. the digest under
+// h of its JSON Web Key in canonical form.  Thumbprints are usually computed
+// with crypto.SHA256.
+func (pk *PublicKey) JWKThumbprint(h crypto.Hash) ([]byte, error) {
+	if !h.Available() {
+		return nil, errors.New("curve25519: hash function is not available")
+	}
+	/* members in lexicographic order, with no whitespace */
+	digest := h.New()
+	digest.Write([]byte(`{"crv":"X25519","kty":"OKP","x":"` + jwkEncoding.EncodeToString(pk[:]) + `"}`))
+	return digest.Sum(nil), nil
+}
+
+func parseJWK(data []byte) (key *jwk, err error) {
+	key = new(jwk)
+	if err = json.Unmarshal(data, key); err != nil {
+		return nil, err
+	}
+	if key.Kty != "OKP" {
+		return nil, errors.New("curve25519: JWK key type is not OKP")
+	}
+	if key.Crv != "X25519" {
+		return nil, errors.New("curve25519: JWK curve is not X25519")
+	}
+	return
+}
+
+func parseJWKPublicKey(key *jwk) (pk *PublicKey, err error) {
+	if key.X == "" {
+		return nil, errors.New("curve25519: JWK has no public key")
+	}
+	x, err := jwkEncoding.DecodeString(key.X)
+	if err != nil {
+		return nil, fmt.Errorf("curve25519: invalid JWK public key: %w", err)
+	}
+	return ParsePublicKey(x)
+}
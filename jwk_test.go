@@ -0,0 +1,87 @@
+package curve25519
+
+import (
+	"crypto"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWK(t *testing.T) {
+	// the keys of RFC 8037 appendix A.6, which are those of RFC 7748
+	publicKey, err := ParseJWKPublicKey([]byte(`{"kty":"OKP","crv":"X25519",
+		"x":"3p7bfXt9wbTTW2HC7OQ1Nz-DQ8hbeGdNrfx-FG-IK08"}`))
+	require.NoError(t, err)
+	require.Equal(t, NewPublicKey(decodeHex(t, "de9edb7d7b7dc1b4d35b61c2ece435373f8343c85b78674dadfc7e146f882b4f")), publicKey)
+
+	data, err := publicKey.MarshalJWK()
+	require.NoError(t, err)
+	require.Equal(t, `{"kty":"OKP","crv":"X25519","x":"3p7bfXt9wbTTW2HC7OQ1Nz-DQ8hbeGdNrfx-FG-IK08"}`, string(data))
+
+	thumbprint, err := publicKey.JWKThumbprint(crypto.SHA256)
+	require.NoError(t, err)
+	require.Equal(t, "giQqigT_IKcuzHl0FVJ3k5ts3_TWNAxvsC08UZsfcM8", base64.RawURLEncoding.EncodeToString(thumbprint))
+
+	privateKey, err := ParseJWKPrivateKey([]byte(`{"kty":"OKP","crv":"X25519",
+		"d":"dwdtCnMYpX08FsFyUbJmRd9ML4frwJkqsXf7pR25LCo",
+		"x":"hSDwCYkwp1R0i33ctD73Wg2_Og0mOBr066SpjqqbTmo"}`))
+	require.NoError(t, err)
+	require.Equal(t, NewPrivateKey(decodeHex(t, "77076d0a7318a57d3c16c17251b26645df4c2f87ebc0992ab177fba51db92c2a")), privateKey)
+
+	// which isn't clamped, so that it is written back clamped
+	data, err = privateKey.MarshalJWK()
+	require.NoError(t, err)
+	require.JSONEq(t, `{"kty":"OKP","crv":"X25519",
+		"d":"cAdtCnMYpX08FsFyUbJmRd9ML4frwJkqsXf7pR25LGo",
+		"x":"hSDwCYkwp1R0i33ctD73Wg2_Og0mOBr066SpjqqbTmo"}`, string(data))
+	parsedPrivateKey, err := ParseJWKPrivateKey(data)
+	require.NoError(t, err)
+	require.Equal(t, privateKey, parsedPrivateKey)
+
+	// a private key without its public key, and a public key taken from a
+	// private JWK
+	_, err = ParseJWKPrivateKey([]byte(`{"kty":"OKP","crv":"X25519","d":"dwdtCnMYpX08FsFyUbJmRd9ML4frwJkqsXf7pR25LCo"}`))
+	require.NoError(t, err)
+	data, err = privateKey.MarshalJWK()
+	require.NoError(t, err)
+	publicKey, err = ParseJWKPublicKey(data)
+	require.NoError(t, err)
+	require.Equal(t, privateKey.Public(), publicKey)
+
+	for i := 0; i < 100; i++ {
+		privateKey := GenerateKeyFrom(reader)
+		data, err := privateKey.MarshalJWK()
+		require.NoError(t, err)
+		parsedPrivateKey, err := ParseJWKPrivateKey(data)
+		require.NoError(t, err)
+		require.Equal(t, privateKey, parsedPrivateKey)
+	}
+
+	for _, data := range []string{
+		`{"kty":"OKP","crv":"X25519","x":"3p7bfXt9wbTTW2HC7OQ1Nz-DQ8hbeGdNrfx-FG-IK08"`,
+		`{"kty":"EC","crv":"X25519","x":"3p7bfXt9wbTTW2HC7OQ1Nz-DQ8hbeGdNrfx-FG-IK08"}`,
+		`{"kty":"OKP","crv":"Ed25519","x":"3p7bfXt9wbTTW2HC7OQ1Nz-DQ8hbeGdNrfx-FG-IK08"}`,
+		`{"crv":"X25519","x":"3p7bfXt9wbTTW2HC7OQ1Nz-DQ8hbeGdNrfx-FG-IK08"}`,
+		`{"kty":"OKP","crv":"X25519"}`,
+		`{"kty":"OKP","crv":"X25519","x":"3p7bfXt9wbTTW2HC7OQ1Nz-DQ8hbeGdNrfx-FG-IK08="}`,
+		`{"kty":"OKP","crv":"X25519","x":"3p7bfXt9wbTTW2HC7OQ1Nz+DQ8hbeGdNrfx+FG+IK08"}`,
+		`{"kty":"OKP","crv":"X25519","x":"3p7bfXt9wbTTW2HC7OQ1Nz-DQ8hbeGdNrfx-FG-IK0"}`,
+		`{"kty":"OKP","crv":"X25519","x":"3p7bfXt9wbTTW2HC7OQ1Nz-DQ8hbeGdNrfx-FG-IK08AA"}`,
+	} {
+		_, err := ParseJWKPublicKey([]byte(data))
+		require.Error(t, err, data)
+	}
+
+	for _, data := range []string{
+		`{"kty":"OKP","crv":"X25519","x":"hSDwCYkwp1R0i33ctD73Wg2_Og0mOBr066SpjqqbTmo"}`,
+		`{"kty":"OKP","crv":"X25519","d":"dwdtCnMYpX08FsFyUbJmRd9ML4frwJkqsXf7pR25LCo",
+			"x":"3p7bfXt9wbTTW2HC7OQ1Nz-DQ8hbeGdNrfx-FG-IK08"}`,
+		`{"kty":"OKP","crv":"X25519","d":"dwdtCnMYpX08FsFyUbJmRd9ML4frwJkqsXf7pR25LCo="}`,
+		`{"kty":"OKP","crv":"X25519","d":"dwdtCnMYpX08FsFyUbJmRd9ML4frwJkqsXf7pR25LC"}`,
+		`{"kty":"OKP","crv":"Ed25519","d":"dwdtCnMYpX08FsFyUbJmRd9ML4frwJkqsXf7pR25LCo"}`,
+	} {
+		_, err := ParseJWKPrivateKey([]byte(data))
+		require.Error(t, err, data)
+	}
+}
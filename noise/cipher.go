@@ -0,0 +1,209 @@
+package noise
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"math"
+
+	"golang.org/x/crypto/blake2s"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Cipher is a cipher function of a Noise protocol.
+type Cipher int
+
+const (
+	ChaChaPoly Cipher = iota
+	AESGCM
+)
+
+func (c Cipher) String() string {
+	switch c {
+	case ChaChaPoly:
+		return "ChaChaPoly"
+	case AESGCM:
+		return "AESGCM"
+	}
+	return "unknown"
+}
+
+/* the AEAD of the cipher, keyed with k, and its 96-bit nonce for n */
+func (c Cipher) aead(k []byte) (aead cipher.AEAD, err error) {
+	switch c {
+	case ChaChaPoly:
+		return chacha20poly1305.New(k)
+	case AESGCM:
+		block, err := aes.NewCipher(k)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	}
+	return nil, errors.New("noise: unknown cipher")
+}
+
+/* 32 bits of zeros followed by the 64-bit nonce, little-endian for
+ * ChaChaPoly and big-endian for AESGCM */
+func (c Cipher) nonce(n uint64) []byte {
+	nonce := make([]byte, 12)
+	if c == AESGCM {
+		binary.BigEndian.PutUint64(nonce[4:], n)
+	} else {
+		binary.LittleEndian.PutUint64(nonce[4:], n)
+	}
+	return nonce
+}
+
+// Hash is a hash function of a Noise protocol.
+type Hash int
+
+const (
+	SHA256 Hash = iota
+	BLAKE2s
+)
+
+func (h Hash) String() string {
+	switch h {
+	case SHA256:
+		return "SHA256"
+	case BLAKE2s:
+		return "BLAKE2s"
+	}
+	return "unknown"
+}
+
+func (h Hash) new() hash.Hash {
+	if h == BLAKE2s {
+		digest, _ := blake2s.New256(nil)
+		return digest
+	}
+	return sha256.New()
+}
+
+func (h Hash) sum(data ...[]byte) []byte {
+	digest := h.new()
+	for _, d := range data {
+		digest.Write(d)
+	}
+	return digest.Sum(nil)
+}
+
+/* HKDF of the Noise specification, with two or three outputs */
+func (h Hash) hkdf(chainingKey, ikm []byte, outputs int) [][]byte {
+	mac := hmac.New(h.new, chainingKey)
+	mac.Write(ikm)
+	tempKey := mac.Sum(nil)
+
+	out := make([][]byte, outputs)
+	var previous []byte
+	for i := range out {
+		mac = hmac.New(h.new, tempKey)
+		mac.Write(previous)
+		mac.Write([]byte{byte(i + 1)})
+		out[i] = mac.Sum(nil)
+		previous = out[i]
+	}
+	return out
+}
+
+// CipherSuite is the cipher and hash functions of a Noise protocol, whose DH
+// function is always 25519.
+type CipherSuite struct {
+	Cipher Cipher
+	Hash   Hash
+}
+
+// Name returns the name of the suite in protocol names, e.g.
+// "25519_ChaChaPoly_BLAKE2s".
+func (s CipherSuite) Name() string {
+	return "25519_" + s.Cipher.String() + "_" + s.Hash.String()
+}
+
+// ErrNonceExhausted is returned when a CipherState has used all its nonces,
+// and must be rekeyed or replaced.
+var ErrNonceExhausted = errors.New("noise: nonce exhausted")
+
+// ErrDecrypt is returned when a message fails authentication.
+var ErrDecrypt = errors.New("noise: message authentication failed")
+
+// CipherState encrypts the transport messages in one direction after a
+// handshake.  It must not be used concurrently.
+type CipherState struct {
+	cipher Cipher
+	k      []byte
+	aead   cipher.AEAD
+	n      uint64
+}
+
+func (cs *CipherState) initializeKey(k []byte) {
+	aead, err := cs.cipher.aead(k)
+	if err != nil {
+		panic(err) /* keys are always 32 bytes */
+	}
+	cs.k, cs.aead, cs.n = k, aead, 0
+}
+
+func (cs *CipherState) hasKey() bool {
+	return cs.aead != nil
+}
+
+// Nonce returns the nonce of the next message.
+func (cs *CipherState) Nonce() uint64 {
+	return cs.n
+}
+
+// SetNonce sets the nonce of the next message, for protocols whose messages
+// may arrive out of order and carry their nonces explicitly.
+func (cs *CipherState) SetNonce(n uint64) {
+	cs.n = n
+}
+
+// Encrypt appends plaintext, encrypted and authenticated along with ad, to
+// out.
+func (cs *CipherState) Encrypt(out, ad, plaintext []byte) ([]byte, error) {
+	if !cs.hasKey() {
+		return append(out, plaintext...), nil
+	}
+	/* 2^64-1 is reserved for Rekey */
+	if cs.n == math.MaxUint64 {
+		return nil, ErrNonceExhausted
+	}
+	out = cs.aead.Seal(out, cs.cipher.nonce(cs.n), plaintext, ad)
+	cs.n++
+	return out, nil
+}
+
+// Decrypt appends the plaintext of ciphertext to out, failing with ErrDecrypt
+// if ciphertext or ad has been tampered with.  The nonce only advances when
+// decryption succeeds.
+func (cs *CipherState) Decrypt(out, ad, ciphertext []byte) ([]byte, error) {
+	if !cs.hasKey() {
+		return append(out, ciphertext...), nil
+	}
+	if cs.n == math.MaxUint64 {
+		return nil, ErrNonceExhausted
+	}
+	out, err := cs.aead.Open(out, cs.cipher.nonce(cs.n), ciphertext, ad)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	cs.n++
+	return out, nil
+}
+
+// Rekey replaces the key with one derived from it, as REKEY of the Noise
+// specification.  The nonce is kept.
+func (cs *CipherState) Rekey() {
+	if !cs.hasKey() {
+		return
+	}
+	k := cs.aead.Seal(nil, cs.cipher.nonce(math.MaxUint64), make([]byte, 32), nil)
+	n := cs.n
+	cs.initializeKey(k[:32])
+	cs.n = n
+}
@@ -0,0 +1,311 @@
+package noise
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	curve25519 "github.com/moonfruit/go-curve25519"
+	"github.com/stretchr/testify/require"
+)
+
+var reader = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+type hexBytes []byte
+
+func (h *hexBytes) UnmarshalText(text []byte) (err error) {
+	*h, err = hex.DecodeString(string(text))
+	return
+}
+
+/* a test vector in the cacophony format */
+type vector struct {
+	ProtocolName     string     `json:"protocol_name"`
+	InitPrologue     hexBytes   `json:"init_prologue"`
+	InitPSKs         []hexBytes `json:"init_psks"`
+	InitStatic       hexBytes   `json:"init_static"`
+	InitEphemeral    hexBytes   `json:"init_ephemeral"`
+	InitRemoteStatic hexBytes   `json:"init_remote_static"`
+	RespPrologue     hexBytes   `json:"resp_prologue"`
+	RespPSKs         []hexBytes `json:"resp_psks"`
+	RespStatic       hexBytes   `json:"resp_static"`
+	RespEphemeral    hexBytes   `json:"resp_ephemeral"`
+	RespRemoteStatic hexBytes   `json:"resp_remote_static"`
+	HandshakeHash    hexBytes   `json:"handshake_hash"`
+	Messages         []struct {
+		Payload    hexBytes `json:"payload"`
+		Ciphertext hexBytes `json:"ciphertext"`
+	} `json:"messages"`
+}
+
+var (
+	patterns = map[string]HandshakePattern{
+		"N":  HandshakeN,
+		"NK": HandshakeNK,
+		"IK": HandshakeIK,
+		"XX": HandshakeXX,
+	}
+	ciphers = map[string]Cipher{
+		"ChaChaPoly": ChaChaPoly,
+		"AESGCM":     AESGCM,
+	}
+	hashes = map[string]Hash{
+		"SHA256":  SHA256,
+		"BLAKE2s": BLAKE2s,
+	}
+)
+
+func parseProtocolName(t *testing.T, name string) (pattern HandshakePattern, suite CipherSuite) {
+	components := strings.Split(name, "_")
+	require.Len(t, components, 5)
+	require.Equal(t, "Noise", components[0])
+	require.Equal(t, "25519", components[2])
+
+	modifiers := strings.Split(components[1], "psk")
+	pattern, ok := patterns[modifiers[0]]
+	require.True(t, ok, name)
+	var positions []int
+	for _, modifier := range modifiers[1:] {
+		position, err := strconv.Atoi(strings.TrimSuffix(modifier, "+"))
+		require.NoError(t, err)
+		positions = append(positions, position)
+	}
+	if len(positions) > 0 {
+		var err error
+		pattern, err = pattern.WithPSK(positions...)
+		require.NoError(t, err)
+	}
+	require.Equal(t, components[1], pattern.Name)
+
+	suite.Cipher, ok = ciphers[components[3]]
+	require.True(t, ok, name)
+	suite.Hash, ok = hashes[components[4]]
+	require.True(t, ok, name)
+	require.Equal(t, name, "Noise_"+pattern.Name+"_"+suite.Name())
+	return
+}
+
+func privateKey(b []byte) *curve25519.PrivateKey {
+	if b == nil {
+		return nil
+	}
+	return curve25519.NewPrivateKey(b)
+}
+
+func publicKey(b []byte) *curve25519.PublicKey {
+	if b == nil {
+		return nil
+	}
+	return curve25519.NewPublicKey(b)
+}
+
+func psks(keys []hexBytes) (psks [][]byte) {
+	for _, key := range keys {
+		psks = append(psks, key)
+	}
+	return
+}
+
+func TestVectors(t *testing.T) {
+	// vectors/cacophony.txt of the cacophony Haskell implementation, as
+	// shipped in testdata of github.com/katzenpost/nyquist, keeping the
+	// vectors of the patterns, modifiers and suites of this package as they
+	// are
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "cacophony.txt"))
+	require.NoError(t, err)
+	var vectors struct {
+		Vectors []vector `json:"vectors"`
+	}
+	require.NoError(t, json.Unmarshal(data, &vectors))
+	require.Len(t, vectors.Vectors, 40)
+
+	for _, v := range vectors.Vectors {
+		pattern, suite := parseProtocolName(t, v.ProtocolName)
+		initiator, err := NewHandshakeState(Config{
+			Pattern:       pattern,
+			CipherSuite:   suite,
+			Initiator:     true,
+			Prologue:      v.InitPrologue,
+			StaticKey:     privateKey(v.InitStatic),
+			PeerStatic:    publicKey(v.InitRemoteStatic),
+			PresharedKeys: psks(v.InitPSKs),
+			Random:        bytes.NewReader(v.InitEphemeral),
+		})
+		require.NoError(t, err, v.ProtocolName)
+		responder, err := NewHandshakeState(Config{
+			Pattern:       pattern,
+			CipherSuite:   suite,
+			Prologue:      v.RespPrologue,
+			StaticKey:     privateKey(v.RespStatic),
+			PeerStatic:    publicKey(v.RespRemoteStatic),
+			PresharedKeys: psks(v.RespPSKs),
+			Random:        bytes.NewReader(v.RespEphemeral),
+		})
+		require.NoError(t, err, v.ProtocolName)
+
+		// messages alternate, starting with the initiator, and so do
+		// transport messages after the handshake but for one-way patterns,
+		// where they all are the initiator's
+		oneWay := len(pattern.Messages) == 1
+		var send, receive [2]*CipherState
+		for i, message := range v.Messages {
+			if i >= len(pattern.Messages) {
+				j := i % 2
+				if oneWay {
+					j = 0
+				}
+				ciphertext, err := send[j].Encrypt(nil, nil, message.Payload)
+				require.NoError(t, err)
+				require.Equal(t, []byte(message.Ciphertext), ciphertext, "%s message %d", v.ProtocolName, i)
+				payload, err := receive[j].Decrypt(nil, nil, ciphertext)
+				require.NoError(t, err)
+				require.Equal(t, []byte(message.Payload), append([]byte{}, payload...))
+				continue
+			}
+
+			writer, reader := initiator, responder
+			if i%2 == 1 {
+				writer, reader = responder, initiator
+			}
+			ciphertext, c1, c2, err := writer.WriteMessage(nil, message.Payload)
+			require.NoError(t, err)
+			require.Equal(t, []byte(message.Ciphertext), ciphertext, "%s message %d", v.ProtocolName, i)
+			payload, d1, d2, err := reader.ReadMessage(nil, ciphertext)
+			require.NoError(t, err)
+			require.Equal(t, []byte(message.Payload), append([]byte{}, payload...))
+
+			if i == len(pattern.Messages)-1 {
+				require.NotNil(t, c1)
+				require.NotNil(t, d1)
+				if writer == initiator {
+					send, receive = [2]*CipherState{c1, d2}, [2]*CipherState{d1, c2}
+				} else {
+					send, receive = [2]*CipherState{d1, c2}, [2]*CipherState{c1, d2}
+				}
+				require.Equal(t, initiator.ChannelBinding(), responder.ChannelBinding())
+				require.Equal(t, []byte(v.HandshakeHash), initiator.ChannelBinding(), v.ProtocolName)
+			} else {
+				require.Nil(t, c1)
+				require.Nil(t, d2)
+			}
+		}
+	}
+}
+
+func TestHandshake(t *testing.T) {
+	initiatorStatic := curve25519.GenerateKeyFrom(reader)
+	responderStatic := curve25519.GenerateKeyFrom(reader)
+	psk := make([]byte, 32)
+	reader.Read(psk)
+
+	pattern, err := HandshakeXX.WithPSK(0, 3)
+	require.NoError(t, err)
+	require.Equal(t, "XXpsk0+psk3", pattern.Name)
+	suite := CipherSuite{AESGCM, BLAKE2s}
+
+	initiator, err := NewHandshakeState(Config{
+		Pattern:       pattern,
+		CipherSuite:   suite,
+		Initiator:     true,
+		Prologue:      []byte("prologue"),
+		StaticKey:     initiatorStatic,
+		PresharedKeys: [][]byte{psk, psk},
+	})
+	require.NoError(t, err)
+	responder, err := NewHandshakeState(Config{
+		Pattern:       pattern,
+		CipherSuite:   suite,
+		Prologue:      []byte("prologue"),
+		StaticKey:     responderStatic,
+		PresharedKeys: [][]byte{psk, psk},
+	})
+	require.NoError(t, err)
+
+	// out of turn
+	_, _, _, err = responder.WriteMessage(nil, nil)
+	require.Equal(t, ErrHandshakeDone, err)
+	_, _, _, err = initiator.ReadMessage(nil, nil)
+	require.Equal(t, ErrHandshakeDone, err)
+
+	message, _, _, err := initiator.WriteMessage(nil, []byte("hello"))
+	require.NoError(t, err)
+	payload, _, _, err := responder.ReadMessage(nil, message)
+	require.NoError(t, err)
+	require.Equal(t, []byte("hello"), payload)
+
+	message, _, _, err = responder.WriteMessage(nil, nil)
+	require.NoError(t, err)
+	// a tampered message leaves the state untouched
+	tampered := append([]byte{}, message...)
+	tampered[len(tampered)-1] ^= 1
+	_, _, _, err = initiator.ReadMessage(nil, tampered)
+	require.Equal(t, ErrDecrypt, err)
+	_, _, _, err = initiator.ReadMessage(nil, message)
+	require.NoError(t, err)
+	require.Equal(t, responderStatic.Public(), initiator.PeerStatic())
+
+	message, c1, c2, err := initiator.WriteMessage(nil, nil)
+	require.NoError(t, err)
+	_, d1, d2, err := responder.ReadMessage(nil, message)
+	require.NoError(t, err)
+	require.Equal(t, initiatorStatic.Public(), responder.PeerStatic())
+	require.Equal(t, initiator.ChannelBinding(), responder.ChannelBinding())
+
+	_, _, _, err = initiator.WriteMessage(nil, nil)
+	require.Equal(t, ErrHandshakeDone, err)
+
+	ciphertext, err := c1.Encrypt(nil, []byte("ad"), []byte("ping"))
+	require.NoError(t, err)
+	plaintext, err := d1.Decrypt(nil, []byte("ad"), ciphertext)
+	require.NoError(t, err)
+	require.Equal(t, []byte("ping"), plaintext)
+	_, err = d1.Decrypt(nil, []byte("ad"), ciphertext)
+	require.Equal(t, ErrDecrypt, err)
+
+	c2.Rekey()
+	d2.Rekey()
+	ciphertext, err = d2.Encrypt(nil, nil, []byte("pong"))
+	require.NoError(t, err)
+	plaintext, err = c2.Decrypt(nil, nil, ciphertext)
+	require.NoError(t, err)
+	require.Equal(t, []byte("pong"), plaintext)
+
+	c1.SetNonce(1<<64 - 1)
+	_, err = c1.Encrypt(nil, nil, nil)
+	require.Equal(t, ErrNonceExhausted, err)
+}
+
+func TestErrors(t *testing.T) {
+	suite := CipherSuite{ChaChaPoly, SHA256}
+	_, err := HandshakeXX.WithPSK(4)
+	require.Error(t, err)
+
+	// a missing psk, and the responder's key for a known-responder pattern
+	pattern, err := HandshakeNK.WithPSK(2)
+	require.NoError(t, err)
+	_, err = NewHandshakeState(Config{Pattern: pattern, CipherSuite: suite, Initiator: true,
+		PeerStatic: curve25519.GenerateKeyFrom(reader).Public()})
+	require.Error(t, err)
+	_, err = NewHandshakeState(Config{Pattern: HandshakeNK, CipherSuite: suite, Initiator: true})
+	require.Error(t, err)
+	responder, err := NewHandshakeState(Config{Pattern: pattern, CipherSuite: suite,
+		StaticKey: curve25519.GenerateKeyFrom(reader), PresharedKeys: [][]byte{make([]byte, 32)}})
+	require.NoError(t, err)
+	require.Error(t, responder.SetPresharedKeys(nil))
+	require.Error(t, responder.SetPresharedKeys([][]byte{make([]byte, 31)}))
+	require.NoError(t, responder.SetPresharedKeys([][]byte{make([]byte, 32)}))
+
+	// a low-order key
+	initiator, err := NewHandshakeState(Config{Pattern: HandshakeN, CipherSuite: suite, Initiator: true,
+		PeerStatic: new(curve25519.PublicKey)})
+	require.NoError(t, err)
+	_, _, _, err = initiator.WriteMessage(nil, nil)
+	require.Equal(t, curve25519.ErrLowOrderPoint, err)
+}
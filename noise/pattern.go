@@ -0,0 +1,111 @@
+package noise
+
+import (
+	"errors"
+	"strconv"
+)
+
+// Token is a token of a handshake pattern.
+type Token int
+
+const (
+	TokenE Token = iota
+	TokenS
+	TokenEE
+	TokenES
+	TokenSE
+	TokenSS
+	TokenPSK
+)
+
+// HandshakePattern is a Noise handshake pattern.  The pre-messages may only
+// contain TokenS.
+type HandshakePattern struct {
+	Name                 string
+	InitiatorPreMessages []Token
+	ResponderPreMessages []Token
+	// Messages alternate between the initiator and the responder, starting
+	// with the initiator.
+	Messages [][]Token
+}
+
+var (
+	// HandshakeN is the one-way pattern to a known responder.
+	HandshakeN = HandshakePattern{
+		Name:                 "N",
+		ResponderPreMessages: []Token{TokenS},
+		Messages: [][]Token{
+			{TokenE, TokenES},
+		},
+	}
+	// HandshakeNK is an anonymous initiator to a known responder.
+	HandshakeNK = HandshakePattern{
+		Name:                 "NK",
+		ResponderPreMessages: []Token{TokenS},
+		Messages: [][]Token{
+			{TokenE, TokenES},
+			{TokenE, TokenEE},
+		},
+	}
+	// HandshakeIK is an initiator sending its static key immediately to a
+	// known responder.
+	HandshakeIK = HandshakePattern{
+		Name:                 "IK",
+		ResponderPreMessages: []Token{TokenS},
+		Messages: [][]Token{
+			{TokenE, TokenES, TokenS, TokenSS},
+			{TokenE, TokenEE, TokenSE},
+		},
+	}
+	// HandshakeXX is mutual authentication where both static keys are
+	// transmitted.
+	HandshakeXX = HandshakePattern{
+		Name: "XX",
+		Messages: [][]Token{
+			{TokenE},
+			{TokenE, TokenEE, TokenS, TokenES},
+			{TokenS, TokenSE},
+		},
+	}
+)
+
+// WithPSK returns the pattern with the pskN modifiers for the given
+// positions: psk0 puts a psk token at the start of the first message, and
+// pskN at the end of the N-th message.  The name becomes e.g. "XXpsk0+psk3".
+func (p HandshakePattern) WithPSK(positions ...int) (HandshakePattern, error) {
+	modified := HandshakePattern{
+		Name:                 p.Name,
+		InitiatorPreMessages: p.InitiatorPreMessages,
+		ResponderPreMessages: p.ResponderPreMessages,
+		Messages:             make([][]Token, len(p.Messages)),
+	}
+	for i, message := range p.Messages {
+		modified.Messages[i] = append([]Token(nil), message...)
+	}
+	for i, position := range positions {
+		switch {
+		case position < 0 || position > len(p.Messages):
+			return HandshakePattern{}, errors.New("noise: invalid psk position")
+		case position == 0:
+			modified.Messages[0] = append([]Token{TokenPSK}, modified.Messages[0]...)
+		default:
+			modified.Messages[position-1] = append(modified.Messages[position-1], TokenPSK)
+		}
+		if i > 0 {
+			modified.Name += "+"
+		}
+		modified.Name += "psk" + strconv.Itoa(position)
+	}
+	return modified, nil
+}
+
+func (p *HandshakePattern) pskCount() (count int) {
+	for _, message := range p.Messages {
+		for _, token := range message {
+			if token == TokenPSK {
+				count++
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,373 @@
+// Package noise implements handshakes of the Noise Protocol Framework
+// (revision 34) with the 25519 DH function, on top of this module's X25519
+// keys.
+//
+// Both parties create a HandshakeState with the same pattern, suite and
+// prologue, then take turns calling WriteMessage and ReadMessage.  The call
+// that processes the last handshake message also returns the two
+// CipherStates for transport messages: the first for messages from the
+// initiator to the responder, and the second for the other direction.
+package noise
+
+import (
+	"crypto/rand"
+	"errors"
+	"io"
+
+	curve25519 "github.com/moonfruit/go-curve25519"
+)
+
+// MaxMessageSize is the maximum size of a Noise message.
+const MaxMessageSize = 65535
+
+const (
+	dhLen  = 32
+	tagLen = 16
+	pskLen = 32
+)
+
+/* SymmetricState of the Noise specification */
+type symmetricState struct {
+	hash Hash
+	cs   CipherState
+	ck   []byte
+	h    []byte
+}
+
+func (ss *symmetricState) initialize(suite CipherSuite, protocolName string) {
+	ss.hash = suite.Hash
+	ss.cs = CipherState{cipher: suite.Cipher}
+	hashLen := suite.Hash.new().Size()
+	if len(protocolName) <= hashLen {
+		ss.h = make([]byte, hashLen)
+		copy(ss.h, protocolName)
+	} else {
+		ss.h = ss.hash.sum([]byte(protocolName))
+	}
+	ss.ck = append([]byte(nil), ss.h...)
+}
+
+func (ss *symmetricState) mixKey(ikm []byte) {
+	out := ss.hash.hkdf(ss.ck, ikm, 2)
+	ss.ck = out[0]
+	ss.cs.initializeKey(out[1][:32])
+}
+
+func (ss *symmetricState) mixHash(data []byte) {
+	ss.h = ss.hash.sum(ss.h, data)
+}
+
+func (ss *symmetricState) mixKeyAndHash(ikm []byte) {
+	out := ss.hash.hkdf(ss.ck, ikm, 3)
+	ss.ck = out[0]
+	ss.mixHash(out[1])
+	ss.cs.initializeKey(out[2][:32])
+}
+
+func (ss *symmetricState) encryptAndHash(out, plaintext []byte) ([]byte, error) {
+	ciphertext, err := ss.cs.Encrypt(out, ss.h, plaintext)
+	if err != nil {
+		return nil, err
+	}
+	ss.mixHash(ciphertext[len(out):])
+	return ciphertext, nil
+}
+
+func (ss *symmetricState) decryptAndHash(out, ciphertext []byte) ([]byte, error) {
+	plaintext, err := ss.cs.Decrypt(out, ss.h, ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	ss.mixHash(ciphertext)
+	return plaintext, nil
+}
+
+func (ss *symmetricState) split() (c1, c2 *CipherState) {
+	out := ss.hash.hkdf(ss.ck, nil, 2)
+	c1 = &CipherState{cipher: ss.cs.cipher}
+	c1.initializeKey(out[0][:32])
+	c2 = &CipherState{cipher: ss.cs.cipher}
+	c2.initializeKey(out[1][:32])
+	return
+}
+
+// Config configures one side of a handshake.
+type Config struct {
+	Pattern     HandshakePattern
+	CipherSuite CipherSuite
+	Initiator   bool
+	Prologue    []byte
+	// StaticKey is the local static key, for patterns that have one.
+	StaticKey *curve25519.PrivateKey
+	// PeerStatic is the remote static key, for patterns where it is known
+	// before the handshake.
+	PeerStatic *curve25519.PublicKey
+	// PresharedKeys holds the 32-byte keys for the psk tokens of the
+	// pattern, in order.
+	PresharedKeys [][]byte
+	// Random is the source of the ephemeral keys, crypto/rand by default.
+	Random io.Reader
+}
+
+// HandshakeState is one side of a handshake.  It must not be used
+// concurrently.
+type HandshakeState struct {
+	ss            symmetricState
+	pattern       HandshakePattern
+	initiator     bool
+	s             *curve25519.PrivateKey
+	e             *curve25519.PrivateKey
+	rs            *curve25519.PublicKey
+	re            *curve25519.PublicKey
+	presharedKeys [][]byte
+	pskMode       bool
+	random        io.Reader
+	message       int
+}
+
+// ErrHandshakeDone is returned when a message is written or read after the
+// handshake has completed, or out of turn.
+var ErrHandshakeDone = errors.New("noise: handshake message out of turn")
+
+// NewHandshakeState starts one side of a handshake.
+func NewHandshakeState(config Config) (hs *HandshakeState, err error) {
+	hs = &HandshakeState{
+		pattern:       config.Pattern,
+		initiator:     config.Initiator,
+		s:             config.StaticKey,
+		rs:            config.PeerStatic,
+		presharedKeys: config.PresharedKeys,
+		random:        config.Random,
+	}
+	if hs.random == nil {
+		hs.random = rand.Reader
+	}
+	pskCount := hs.pattern.pskCount()
+	if len(hs.presharedKeys) != pskCount {
+		return nil, errors.New("noise: wrong number of preshared keys for the pattern")
+	}
+	for _, psk := range hs.presharedKeys {
+		if len(psk) != pskLen {
+			return nil, errors.New("noise: preshared keys must be 32 bytes")
+		}
+	}
+	hs.pskMode = pskCount > 0
+
+	hs.ss.initialize(config.CipherSuite, "Noise_"+hs.pattern.Name+"_"+config.CipherSuite.Name())
+	hs.ss.mixHash(config.Prologue)
+
+	/* the initiator's pre-message keys are hashed first, by both sides */
+	initiatorStatic, responderStatic := hs.localStatic(), hs.rs
+	if !hs.initiator {
+		initiatorStatic, responderStatic = hs.rs, hs.localStatic()
+	}
+	for _, pre := range []struct {
+		tokens []Token
+		static *curve25519.PublicKey
+	}{
+		{hs.pattern.InitiatorPreMessages, initiatorStatic},
+		{hs.pattern.ResponderPreMessages, responderStatic},
+	} {
+		for _, token := range pre.tokens {
+			if token != TokenS {
+				return nil, errors.New("noise: unsupported pre-message token")
+			}
+			if pre.static == nil {
+				return nil, errors.New("noise: missing static key for pre-message")
+			}
+			hs.ss.mixHash(pre.static[:])
+		}
+	}
+	return
+}
+
+// PeerStatic returns the remote static key, once it is known.
+func (hs *HandshakeState) PeerStatic() *curve25519.PublicKey {
+	return hs.rs
+}
+
+// ChannelBinding returns the handshake hash, which is unique to the session
+// once the handshake has completed.
+func (hs *HandshakeState) ChannelBinding() []byte {
+	return append([]byte(nil), hs.ss.h...)
+}
+
+// SetPresharedKeys replaces the keys for the psk tokens not processed yet,
+// for a responder that picks them by the initiator's static key.
+func (hs *HandshakeState) SetPresharedKeys(keys [][]byte) error {
+	if len(keys) != len(hs.presharedKeys) {
+		return errors.New("noise: wrong number of preshared keys for the pattern")
+	}
+	for _, psk := range keys {
+		if len(psk) != pskLen {
+			return errors.New("noise: preshared keys must be 32 bytes")
+		}
+	}
+	hs.presharedKeys = keys
+	return nil
+}
+
+func (hs *HandshakeState) localStatic() *curve25519.PublicKey {
+	if hs.s == nil {
+		return nil
+	}
+	return hs.s.Public()
+}
+
+/* whether this side writes the next message */
+func (hs *HandshakeState) writesNext() bool {
+	return (hs.message%2 == 0) == hs.initiator
+}
+
+// WriteMessage appends the next handshake message, carrying payload, to
+// out.  After the last message, it also returns the transport CipherStates.
+func (hs *HandshakeState) WriteMessage(out, payload []byte) (message []byte, c1, c2 *CipherState, err error) {
+	if hs.message >= len(hs.pattern.Messages) || !hs.writesNext() {
+		return nil, nil, nil, ErrHandshakeDone
+	}
+	start := len(out)
+	for _, token := range hs.pattern.Messages[hs.message] {
+		switch token {
+		case TokenE:
+			if hs.e, err = curve25519.GenerateKeyWithReader(hs.random); err != nil {
+				return nil, nil, nil, err
+			}
+			e := hs.e.Public()
+			out = append(out, e[:]...)
+			hs.ss.mixHash(e[:])
+			if hs.pskMode {
+				hs.ss.mixKey(e[:])
+			}
+		case TokenS:
+			if hs.s == nil {
+				return nil, nil, nil, errors.New("noise: missing static key")
+			}
+			if out, err = hs.ss.encryptAndHash(out, hs.s.Public()[:]); err != nil {
+				return nil, nil, nil, err
+			}
+		case TokenPSK:
+			hs.mixPSK()
+		default:
+			if err = hs.mixDH(token); err != nil {
+				return nil, nil, nil, err
+			}
+		}
+	}
+	if out, err = hs.ss.encryptAndHash(out, payload); err != nil {
+		return nil, nil, nil, err
+	}
+	if len(out)-start > MaxMessageSize {
+		return nil, nil, nil, errors.New("noise: message too long")
+	}
+	c1, c2 = hs.next()
+	return out, c1, c2, nil
+}
+
+// ReadMessage processes the next handshake message and appends its payload
+// to out.  After the last message, it also returns the transport
+// CipherStates.
+func (hs *HandshakeState) ReadMessage(out, message []byte) (payload []byte, c1, c2 *CipherState, err error) {
+	if hs.message >= len(hs.pattern.Messages) || hs.writesNext() {
+		return nil, nil, nil, ErrHandshakeDone
+	}
+	if len(message) > MaxMessageSize {
+		return nil, nil, nil, errors.New("noise: message too long")
+	}
+	/* work on a copy so that a failed message leaves the state untouched */
+	ss := hs.ss
+	rs, re, presharedKeys := hs.rs, hs.re, hs.presharedKeys
+	defer func() {
+		if err != nil {
+			hs.ss, hs.rs, hs.re, hs.presharedKeys = ss, rs, re, presharedKeys
+		}
+	}()
+	for _, token := range hs.pattern.Messages[hs.message] {
+		switch token {
+		case TokenE:
+			if len(message) < dhLen {
+				return nil, nil, nil, errors.New("noise: message too short")
+			}
+			hs.re = curve25519.NewPublicKey(message[:dhLen])
+			message = message[dhLen:]
+			hs.ss.mixHash(hs.re[:])
+			if hs.pskMode {
+				hs.ss.mixKey(hs.re[:])
+			}
+		case TokenS:
+			n := dhLen
+			if hs.ss.cs.hasKey() {
+				n += tagLen
+			}
+			if len(message) < n {
+				return nil, nil, nil, errors.New("noise: message too short")
+			}
+			var static []byte
+			if static, err = hs.ss.decryptAndHash(nil, message[:n]); err != nil {
+				return nil, nil, nil, err
+			}
+			hs.rs = curve25519.NewPublicKey(static)
+			message = message[n:]
+		case TokenPSK:
+			hs.mixPSK()
+		default:
+			if err = hs.mixDH(token); err != nil {
+				return nil, nil, nil, err
+			}
+		}
+	}
+	if payload, err = hs.ss.decryptAndHash(out, message); err != nil {
+		return nil, nil, nil, err
+	}
+	c1, c2 = hs.next()
+	return payload, c1, c2, nil
+}
+
+func (hs *HandshakeState) mixPSK() {
+	hs.ss.mixKeyAndHash(hs.presharedKeys[0])
+	hs.presharedKeys = hs.presharedKeys[1:]
+}
+
+/* mixes in the DH output of ee, es, se or ss, where es is always between the
+ * initiator's ephemeral key and the responder's static key */
+func (hs *HandshakeState) mixDH(token Token) error {
+	var local *curve25519.PrivateKey
+	var remote *curve25519.PublicKey
+	switch token {
+	case TokenEE:
+		local, remote = hs.e, hs.re
+	case TokenES:
+		if hs.initiator {
+			local, remote = hs.e, hs.rs
+		} else {
+			local, remote = hs.s, hs.re
+		}
+	case TokenSE:
+		if hs.initiator {
+			local, remote = hs.s, hs.re
+		} else {
+			local, remote = hs.e, hs.rs
+		}
+	case TokenSS:
+		local, remote = hs.s, hs.rs
+	default:
+		return errors.New("noise: unknown token")
+	}
+	if local == nil || remote == nil {
+		return errors.New("noise: missing key for DH")
+	}
+	dh, err := local.SharedSecretChecked(remote)
+	if err != nil {
+		return err
+	}
+	hs.ss.mixKey(dh)
+	return nil
+}
+
+/* moves on to the next message, splitting after the last one */
+func (hs *HandshakeState) next() (c1, c2 *CipherState) {
+	hs.message++
+	if hs.message < len(hs.pattern.Messages) {
+		return nil, nil
+	}
+	return hs.ss.split()
+}
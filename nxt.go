@@ -0,0 +1,359 @@
+package curve25519
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+/* Reed-Solomon account addresses of Nxt and Ardor: the 13 base-32 digits of
+ * an account ID, least significant first, followed by 4 check symbols of a
+ * code over GF(32) shortened from length 31.  The digits are at positions 0
+ * to 12 of the full code and the check symbols at 27 to 30, and the symbols
+ * are written in the order of addressOrder */
+
+const (
+	addressAlphabet = "23456789ABCDEFGHJKLMNPQRSTUVWXYZ"
+	addressDigits   = 13
+	addressChecks   = 4
+	addressSymbols  = addressDigits + addressChecks
+)
+
+var (
+	addressOrder = [addressSymbols]int{3, 2, 1, 0, 7, 6, 5, 4, 13, 14, 15, 16, 12, 8, 9, 10, 11}
+
+	/* powers and logarithms of the generator of GF(32) = GF(2)[x]/(x^5+x^2+1) */
+	gf32Exp = [31]byte{1, 2, 4, 8, 16, 5, 10, 20, 13, 26, 17, 7, 14, 28, 29, 31, 27, 19, 3, 6, 12, 24, 21, 15, 30, 25, 23, 11, 22, 9, 18}
+	gf32Log = [32]byte{0, 0, 1, 18, 2, 5, 19, 11, 3, 29, 6, 27, 20, 8, 12, 23, 4, 10, 30, 17, 7, 22, 28, 26, 21, 25, 9, 16, 13, 14, 24, 15}
+
+	/* the generator polynomial, (x-a)(x-a^2)(x-a^3)(x-a^4), without its
+	 * leading coefficient, lowest first */
+	addressGenerator = [addressChecks]byte{17, 9, 6, 30}
+)
+
+// AccountID returns the Nxt account ID of pk, the first 8 bytes of its
+// SHA-256 in little-endian.  Ardor and the other Nxt-derived chains use the
+// same IDs.
+func (pk *PublicKey) AccountID() uint64 {
+	digest := sha256.Sum256(pk[:])
+	return binary.LittleEndian.Uint64(digest[:8])
+}
+
+// NewPrivateKeyFromPassphrase returns the private key of a Nxt secret phrase,
+// the SHA-256 of its UTF-8, clamped, as the Nxt and Ardor wallets derive it.
+func NewPrivateKeyFromPassphrase(phrase string) (sk *PrivateKey) {
+	digest := sha256.Sum256([]byte(phrase))
+	return NewPrivateKey(digest[:])
+}
+
+// NewPublicKeyFromPassphrase returns the public key of a Nxt secret phrase.
+func NewPublicKeyFromPassphrase(phrase string) (pk *PublicKey) {
+	return NewPrivateKeyFromPassphrase(phrase).Public()
+}
+
+// GeneratePassphrase returns a secret phrase of words from wordList chosen
+// uniformly with reader, as many as it takes for 128 bits of entropy: 12 for
+// the 1626 words of the Nxt wallet.  The words must be distinct, and without
+// spaces.
+func GeneratePassphrase(reader io.Reader, wordList []string) (string, error) {
+	if len(wordList) < 2 || uint64(len(wordList)) > math.MaxUint32 {
+		return "", errors.New("curve25519: invalid word list length")
+	}
+	seen := make(map[string]bool, len(wordList))
+	for _, word := range wordList {
+		if word == "" || strings.ContainsAny(word, " \t\r\n") || seen[word] {
+			return "", fmt.Errorf("curve25519: invalid word %q in word list", word)
+		}
+		seen[word] = true
+	}
+
+	n := uint32(len(wordList))
+	count := int(math.Ceil(128 / math.Log2(float64(n))))
+	/* rejecting the values past the last multiple of n, which would favor
+	 * the first words */
+	limit := math.MaxUint32 - math.MaxUint32%n
+	words := make([]string, 0, count)
+	var b [4]byte
+	for len(words) < count {
+		if _, err := io.ReadFull(reader, b[:]); err != nil {
+			return "", fmt.Errorf("curve25519: failed to read random bytes: %w", err)
+		}
+		if v := binary.LittleEndian.Uint32(b[:]); v < limit {
+			words = append(words, wordList[v%n])
+		}
+	}
+	return strings.Join(words, " "), nil
+}
+
+// AccountAddress returns the Reed-Solomon address of the account id, such as
+// NXT-MRCC-2YLS-8M54-3CMAJ for the prefix NXT.  An empty prefix gives the
+// address without one.
+func AccountAddress(prefix string, id uint64) string {
+	return formatAddress(prefix, encodeAccountID(id))
+}
+
+// ParseAccountAddress parses the Reed-Solomon address of an account with
+// prefix, which may be empty.  It ignores case, and rejects addresses with
+// wrong symbols, which CorrectAccountAddress may be able to fix.
+func ParseAccountAddress(prefix, address string) (id uint64, err error) {
+	codeword, err := parseAddressSymbols(prefix, address)
+	if err != nil {
+		return 0, err
+	}
+	if !gf32IsZero(addressSyndromes(codeword)) {
+		return 0, errors.New("curve25519: invalid account address checksum")
+	}
+	return decodeAccountID(codeword)
+}
+
+// CorrectAccountAddress corrects up to two wrong symbols in the Reed-Solomon
+// address of an account with prefix, and returns the corrected address.  As
+// the address may then be of another account than meant, it is for
+// suggesting to the user rather than for using as is.
+func CorrectAccountAddress(prefix, address string) (string, error) {
+	codeword, err := parseAddressSymbols(prefix, address)
+	if err != nil {
+		return "", err
+	}
+	if err = correctAddressSymbols(&codeword); err != nil {
+		return "", err
+	}
+	id, err := decodeAccountID(codeword)
+	if err != nil {
+		return "", err
+	}
+	return AccountAddress(prefix, id), nil
+}
+
+func formatAddress(prefix string, codeword [addressSymbols]byte) string {
+	var b strings.Builder
+	if prefix != "" {
+		b.WriteString(prefix)
+		b.WriteByte('-')
+	}
+	for i, position := range addressOrder {
+		b.WriteByte(addressAlphabet[codeword[position]])
+		if i%4 == 3 && i < addressDigits {
+			b.WriteByte('-')
+		}
+	}
+	return b.String()
+}
+
+func gf32Mul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gf32Exp[(int(gf32Log[a])+int(gf32Log[b]))%31]
+}
+
+func gf32Div(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return gf32Exp[(int(gf32Log[a])+31-int(gf32Log[b]))%31]
+}
+
+/* a^n, for any n */
+func gf32Pow(n int) byte {
+	return gf32Exp[(n%31+31)%31]
+}
+
+func gf32IsZero(values []byte) bool {
+	for _, v := range values {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+/* the polynomial p, lowest coefficient first, at x */
+func gf32Eval(p []byte, x byte) byte {
+	var y byte
+	for i := len(p) - 1; i >= 0; i-- {
+		y = gf32Mul(y, x) ^ p[i]
+	}
+	return y
+}
+
+/* the position in the full code of the symbol i of a codeword */
+func addressPosition(i int) int {
+	if i < addressDigits {
+		return i
+	}
+	return i + 31 - addressSymbols
+}
+
+/* the digits of id and their check symbols */
+func encodeAccountID(id uint64) (codeword [addressSymbols]byte) {
+	for i := 0; i < addressDigits; i++ {
+		codeword[i] = byte(id >> uint(5*i) & 31)
+	}
+	setAddressChecks(&codeword)
+	return
+}
+
+/* sets the check symbols to the remainder of the digits shifted past them,
+ * by the generator */
+func setAddressChecks(codeword *[addressSymbols]byte) {
+	var checks [addressChecks]byte
+	for i := addressDigits - 1; i >= 0; i-- {
+		feedback := codeword[i] ^ checks[addressChecks-1]
+		for j := addressChecks - 1; j > 0; j-- {
+			checks[j] = checks[j-1] ^ gf32Mul(addressGenerator[j], feedback)
+		}
+		checks[0] = gf32Mul(addressGenerator[0], feedback)
+	}
+	copy(codeword[addressDigits:], checks[:])
+}
+
+func decodeAccountID(codeword [addressSymbols]byte) (uint64, error) {
+	/* 13 digits hold 65 bits */
+	if codeword[addressDigits-1] >= 16 {
+		return 0, errors.New("curve25519: account address out of range")
+	}
+	var id uint64
+	for i := addressDigits - 1; i >= 0; i-- {
+		id = id<<5 | uint64(codeword[i])
+	}
+	return id, nil
+}
+
+func parseAddressSymbols(prefix, address string) (codeword [addressSymbols]byte, err error) {
+	address, ok := upperASCII(address)
+	if !ok {
+		return codeword, errors.New("curve25519: invalid account address character")
+	}
+	if prefix != "" {
+		if prefix, ok = upperASCII(prefix); !ok {
+			return codeword, errors.New("curve25519: invalid account address prefix")
+		}
+		prefix += "-"
+		if !strings.HasPrefix(address, prefix) {
+			return codeword, errors.New("curve25519: account address has the wrong prefix")
+		}
+		address = address[len(prefix):]
+	}
+	n := 0
+	for i := 0; i < len(address); i++ {
+		if address[i] == '-' {
+			continue
+		}
+		v := strings.IndexByte(addressAlphabet, address[i])
+		if v < 0 {
+			return codeword, errors.New("curve25519: invalid account address character")
+		}
+		if n == addressSymbols {
+			return codeword, errors.New("curve25519: account address is too long")
+		}
+		codeword[addressOrder[n]] = byte(v)
+		n++
+	}
+	if n != addressSymbols {
+		return codeword, errors.New("curve25519: account address is too short")
+	}
+	return codeword, nil
+}
+
+/* s with ASCII letters in upper case, and false if it has any other byte
+ * past ASCII, which Unicode case mapping could turn into a letter */
+func upperASCII(s string) (string, bool) {
+	b := []byte(s)
+	for i, c := range b {
+		if c >= 0x80 {
+			return "", false
+		}
+		if c >= 'a' && c <= 'z' {
+			b[i] = c &^ 0x20
+		}
+	}
+	return string(b), true
+}
+
+/* the codeword evaluated at a, a^2, a^3 and a^4, all zero when it is valid */
+func addressSyndromes(codeword [addressSymbols]byte) []byte {
+	syndromes := make([]byte, addressChecks)
+	for i := range syndromes {
+		for j, c := range codeword {
+			syndromes[i] ^= gf32Mul(c, gf32Pow((i+1)*addressPosition(j)))
+		}
+	}
+	return syndromes
+}
+
+/* corrects up to two symbols in place: Berlekamp-Massey for the error
+ * locator, a search of the positions of the shortened code for its roots,
+ * and Forney for the error values */
+func correctAddressSymbols(codeword *[addressSymbols]byte) error {
+	syndromes := addressSyndromes(*codeword)
+	if gf32IsZero(syndromes) {
+		return nil
+	}
+
+	locator, previous := []byte{1}, []byte{1}
+	errs, shift, discrepancy := 0, 1, byte(1)
+	for n, s := range syndromes {
+		d := s
+		for i := 1; i <= errs && i < len(locator); i++ {
+			d ^= gf32Mul(locator[i], syndromes[n-i])
+		}
+		if d == 0 {
+			shift++
+			continue
+		}
+		next := make([]byte, len(locator))
+		copy(next, locator)
+		for len(next) < len(previous)+shift {
+			next = append(next, 0)
+		}
+		for i, p := range previous {
+			next[i+shift] ^= gf32Mul(gf32Div(d, discrepancy), p)
+		}
+		if 2*errs <= n {
+			errs, previous, discrepancy, shift = n+1-errs, locator, d, 1
+		} else {
+			shift++
+		}
+		locator = next
+	}
+	if errs > addressChecks/2 {
+		return errors.New("curve25519: too many errors in account address")
+	}
+
+	/* the evaluator, syndromes times locator modulo x^4 */
+	evaluator := make([]byte, addressChecks)
+	for i := range evaluator {
+		for j := 0; j <= i && j < len(locator); j++ {
+			evaluator[i] ^= gf32Mul(locator[j], syndromes[i-j])
+		}
+	}
+
+	/* the formal derivative of the locator, of its odd terms only */
+	derivative := make([]byte, len(locator))
+	for i := 1; i < len(locator); i += 2 {
+		derivative[i-1] = locator[i]
+	}
+
+	found := 0
+	for j := range codeword {
+		x := gf32Pow(-addressPosition(j))
+		if gf32Eval(locator, x) != 0 {
+			continue
+		}
+		d := gf32Eval(derivative, x)
+		if d == 0 {
+			return errors.New("curve25519: too many errors in account address")
+		}
+		codeword[j] ^= gf32Div(gf32Eval(evaluator, x), d)
+		found++
+	}
+	if found != errs || !gf32IsZero(addressSyndromes(*codeword)) {
+		return errors.New("curve25519: too many errors in account address")
+	}
+	return nil
+}
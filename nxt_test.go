@@ -0,0 +1,154 @@
+package curve25519
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountID(t *testing.T) {
+	// the genesis account of Nxt
+	publicKey, err := hex.DecodeString("1259ec21d31a30898d7cd1609f80d9668b4778e3d97e941044b39f0c44d2e51b")
+	require.NoError(t, err)
+	id := NewPublicKey(publicKey).AccountID()
+	require.Equal(t, uint64(1739068987193023818), id)
+	require.Equal(t, "NXT-MRCC-2YLS-8M54-3CMAJ", AccountAddress("NXT", id))
+	require.Equal(t, "ARDOR-MRCC-2YLS-8M54-3CMAJ", AccountAddress("ARDOR", id))
+}
+
+func TestAccountAddress(t *testing.T) {
+	for _, v := range []struct {
+		id      uint64
+		address string
+	}{
+		{1739068987193023818, "MRCC-2YLS-8M54-3CMAJ"},
+		{0, "2222-2222-2222-22222"},
+	} {
+		require.Equal(t, v.address, AccountAddress("", v.id))
+		id, err := ParseAccountAddress("", v.address)
+		require.NoError(t, err)
+		require.Equal(t, v.id, id)
+		id, err = ParseAccountAddress("NXT", "nxt-"+strings.ToLower(v.address))
+		require.NoError(t, err)
+		require.Equal(t, v.id, id)
+	}
+
+	for i := 0; i < 100; i++ {
+		id := reader.Uint64()
+		address := AccountAddress("NXT", id)
+		parsed, err := ParseAccountAddress("NXT", address)
+		require.NoError(t, err)
+		require.Equal(t, id, parsed)
+
+		// up to two wrong symbols are corrected
+		symbols := []byte(strings.ReplaceAll(address[4:], "-", ""))
+		for errs := 1; errs <= 2; errs++ {
+			wrong := append([]byte(nil), symbols...)
+			for _, j := range reader.Perm(len(wrong))[:errs] {
+				wrong[j] = addressAlphabet[(strings.IndexByte(addressAlphabet, wrong[j])+1+reader.Intn(31))%32]
+			}
+			_, err = ParseAccountAddress("NXT", "NXT-"+string(wrong))
+			require.Error(t, err)
+			corrected, err := CorrectAccountAddress("NXT", "NXT-"+string(wrong))
+			require.NoError(t, err, string(wrong))
+			require.Equal(t, address, corrected)
+		}
+	}
+	corrected, err := CorrectAccountAddress("", "MRCC-2YLS-8M54-3CMAJ")
+	require.NoError(t, err)
+	require.Equal(t, "MRCC-2YLS-8M54-3CMAJ", corrected)
+	// three wrong symbols are too many
+	_, err = CorrectAccountAddress("", "MRCD-3YLS-8M54-3CMAK")
+	require.Error(t, err)
+
+	// 13 digits hold more than 64 bits
+	var codeword [addressSymbols]byte
+	codeword[addressDigits-1] = 16
+	setAddressChecks(&codeword)
+	tooLarge := formatAddress("NXT", codeword)
+
+	for _, s := range []string{
+		"MRCC-2YLS-8M54-3CMAJ",
+		"NXT-MRCC-2YLS-8M54-3CMA",
+		"NXT-MRCC-2YLS-8M54-3CMAJ2",
+		"NXT-MRCC-2YLS-8M54-3CMA0",
+		"NXT-MRCC-2YLS-8M54-3CMAK",
+		"ARDOR-MRCC-2YLS-8M54-3CMAJ",
+		// with a long s, which Unicode upper-cases to S
+		"NXT-MRCC-2YL\u017f-8M54-3CMAJ",
+		tooLarge,
+	} {
+		_, err := ParseAccountAddress("NXT", s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestPassphrase(t *testing.T) {
+	// secret phrases and their keys and accounts in the Nxt wallet
+	file, err := os.Open(filepath.Join("testdata", "passphrase.txt"))
+	require.NoError(t, err)
+	defer file.Close()
+
+	for {
+		var hexPhrase, hexPublicKey, address string
+		n, err := fmt.Fscanln(file, &hexPhrase, &hexPublicKey, &address)
+		if n == 0 && err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		phrase, err := hex.DecodeString(hexPhrase)
+		require.NoError(t, err)
+		expected := new(PublicKey)
+		require.NoError(t, expected.UnmarshalText([]byte(hexPublicKey)))
+
+		privateKey := NewPrivateKeyFromPassphrase(string(phrase))
+		require.Equal(t, expected, privateKey.Public())
+		require.Equal(t, expected, NewPublicKeyFromPassphrase(string(phrase)))
+		require.Equal(t, address, AccountAddress("NXT", expected.AccountID()))
+	}
+}
+
+func TestGeneratePassphrase(t *testing.T) {
+	wordList := make([]string, 7776)
+	for i := range wordList {
+		wordList[i] = fmt.Sprintf("word%d", i)
+	}
+	phrase, err := GeneratePassphrase(reader, wordList[:1626])
+	require.NoError(t, err)
+	words := strings.Split(phrase, " ")
+	require.Len(t, words, 12)
+	for _, word := range words {
+		require.Contains(t, wordList[:1626], word)
+	}
+	another, err := GeneratePassphrase(reader, wordList[:1626])
+	require.NoError(t, err)
+	require.NotEqual(t, phrase, another)
+
+	// as many words as 128 bits take
+	for _, v := range []struct{ size, count int }{{2, 128}, {256, 16}, {2048, 12}, {7776, 10}} {
+		phrase, err = GeneratePassphrase(reader, wordList[:v.size])
+		require.NoError(t, err)
+		require.Len(t, strings.Split(phrase, " "), v.count)
+	}
+
+	for _, invalid := range [][]string{
+		nil,
+		{"one"},
+		{"one", "two", "one"},
+		{"one", ""},
+		{"one", "two three"},
+	} {
+		_, err = GeneratePassphrase(reader, invalid)
+		require.Error(t, err, invalid)
+	}
+	_, err = GeneratePassphrase(bytes.NewReader(make([]byte, 12)), wordList)
+	require.Error(t, err)
+}
@@ -0,0 +1,102 @@
+package curve25519
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+)
+
+// oidX25519 is the algorithm identifier of X25519 keys, from RFC 8410.
+var oidX25519 = asn1.ObjectIdentifier{1, 3, 101, 110}
+
+/* PKCS #8 PrivateKeyInfo, or the OneAsymmetricKey of RFC 5958 which extends
+ * it with an optional public key */
+type pkcs8 struct {
+	Version    int
+	Algorithm  pkix.AlgorithmIdentifier
+	PrivateKey []byte
+	Attributes asn1.RawValue  `asn1:"optional,tag:0"`
+	PublicKey  asn1.BitString `asn1:"optional,tag:1"`
+}
+
+/* X.509 SubjectPublicKeyInfo */
+type publicKeyInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+// MarshalPKCS8PrivateKey returns sk as a DER-encoded PKCS #8 PrivateKeyInfo,
+// as described in RFC 8410 and used by "PRIVATE KEY" PEM blocks.  The key is
+// written clamped, so a key parsed from an unclamped file is written back as
+// other bytes, of the same key.
+func MarshalPKCS8PrivateKey(sk *PrivateKey) ([]byte, error) {
+	/* the private key is itself an OCTET STRING (CurvePrivateKey) */
+	curvePrivateKey, err := asn1.Marshal(sk.raw[:])
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(pkcs8{
+		Algorithm:  pkix.AlgorithmIdentifier{Algorithm: oidX25519},
+		PrivateKey: curvePrivateKey,
+	})
+}
+
+// ParsePKCS8PrivateKey parses a DER-encoded PKCS #8 X25519 private key.  The
+// key is clamped as NewPrivateKey does, so MarshalPKCS8PrivateKey doesn't give
+// back der when it isn't, and a public key in a version 2 (RFC 5958) structure
+// is ignored.
+func ParsePKCS8PrivateKey(der []byte) (sk *PrivateKey, err error) {
+	var info pkcs8
+	if rest, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, err
+	} else if len(rest) > 0 {
+		return nil, errors.New("curve25519: trailing data after PKCS #8 private key")
+	}
+	if info.Version != 0 && info.Version != 1 {
+		return nil, errors.New("curve25519: unsupported PKCS #8 version")
+	}
+	if !info.Algorithm.Algorithm.Equal(oidX25519) {
+		return nil, errors.New("curve25519: PKCS #8 private key is not an X25519 key")
+	}
+	if len(info.Algorithm.Parameters.FullBytes) != 0 {
+		return nil, errors.New("curve25519: X25519 key must not have algorithm parameters")
+	}
+	var curvePrivateKey []byte
+	if rest, err := asn1.Unmarshal(info.PrivateKey, &curvePrivateKey); err != nil {
+		return nil, err
+	} else if len(rest) > 0 {
+		return nil, errors.New("curve25519: trailing data after X25519 private key")
+	}
+	return ParsePrivateKey(curvePrivateKey)
+}
+
+// MarshalPKIXPublicKey returns pk as a DER-encoded X.509
+// SubjectPublicKeyInfo, as described in RFC 8410 and used by "PUBLIC KEY" PEM
+// blocks.
+func MarshalPKIXPublicKey(pk *PublicKey) ([]byte, error) {
+	return asn1.Marshal(publicKeyInfo{
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidX25519},
+		PublicKey: asn1.BitString{Bytes: pk[:], BitLength: 8 * len(pk)},
+	})
+}
+
+// ParsePKIXPublicKey parses a DER-encoded X.509 SubjectPublicKeyInfo holding
+// an X25519 public key.
+func ParsePKIXPublicKey(der []byte) (pk *PublicKey, err error) {
+	var info publicKeyInfo
+	if rest, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, err
+	} else if len(rest) > 0 {
+		return nil, errors.New("curve25519: trailing data after X.509 public key")
+	}
+	if !info.Algorithm.Algorithm.Equal(oidX25519) {
+		return nil, errors.New("curve25519: X.509 public key is not an X25519 key")
+	}
+	if len(info.Algorithm.Parameters.FullBytes) != 0 {
+		return nil, errors.New("curve25519: X25519 key must not have algorithm parameters")
+	}
+	if info.PublicKey.BitLength != 8*len(info.PublicKey.Bytes) {
+		return nil, errors.New("curve25519: X.509 public key is not a whole number of bytes")
+	}
+	return ParsePublicKey(info.PublicKey.Bytes)
+}
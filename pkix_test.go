@@ -0,0 +1,143 @@
+package curve25519
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func readPEM(t *testing.T, name, blockType string) []byte {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	block, rest := pem.Decode(data)
+	require.NotNil(t, block)
+	require.Empty(t, rest)
+	require.Equal(t, blockType, block.Type)
+	return block.Bytes
+}
+
+func TestPKIX(t *testing.T) {
+	// generated by "openssl genpkey -algorithm X25519", which clamps the key
+	privateDER := readPEM(t, "x25519_private.pem", "PRIVATE KEY")
+	publicDER := readPEM(t, "x25519_public.pem", "PUBLIC KEY")
+
+	privateKey, err := ParsePKCS8PrivateKey(privateDER)
+	require.NoError(t, err)
+	require.Equal(t, decodeHex(t, "c0b9239b8aa7cef99add01585ac3af390c8164ab7c227a904fd94a17362b4147"), privateKey.Bytes())
+	publicKey, err := ParsePKIXPublicKey(publicDER)
+	require.NoError(t, err)
+	require.Equal(t, publicKey, privateKey.Public())
+
+	der, err := MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+	require.Equal(t, privateDER, der)
+	der, err = MarshalPKIXPublicKey(publicKey)
+	require.NoError(t, err)
+	require.Equal(t, publicDER, der)
+
+	// OpenSSL clamps the keys it generates, but not those it imports: this is
+	// the unclamped private key of RFC 7748 section 6.1, converted by
+	// "openssl pkey -inform DER", with its public key by "openssl pkey -pubout"
+	privateDER = readPEM(t, "x25519_unclamped_private.pem", "PRIVATE KEY")
+	publicDER = readPEM(t, "x25519_unclamped_public.pem", "PUBLIC KEY")
+
+	privateKey, err = ParsePKCS8PrivateKey(privateDER)
+	require.NoError(t, err)
+	require.Equal(t, decodeHex(t, "70076d0a7318a57d3c16c17251b26645df4c2f87ebc0992ab177fba51db92c6a"), privateKey.Bytes())
+	publicKey, err = ParsePKIXPublicKey(publicDER)
+	require.NoError(t, err)
+	require.Equal(t, NewPublicKey(decodeHex(t, "8520f0098930a754748b7ddcb43ef75a0dbf3a0d26381af4eba4a98eaa9b4e6a")), publicKey)
+	require.Equal(t, publicKey, privateKey.Public())
+
+	// which is written back clamped, as other bytes of the same key
+	der, err = MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+	require.NotEqual(t, privateDER, der)
+	require.Equal(t, privateDER[:len(privateDER)-32], der[:len(der)-32])
+	require.Equal(t, privateKey.Bytes(), der[len(der)-32:])
+	parsedPrivateKey, err := ParsePKCS8PrivateKey(der)
+	require.NoError(t, err)
+	require.Equal(t, privateKey, parsedPrivateKey)
+	der, err = MarshalPKIXPublicKey(publicKey)
+	require.NoError(t, err)
+	require.Equal(t, publicDER, der)
+
+	for i := 0; i < 100; i++ {
+		privateKey := GenerateKeyFrom(reader)
+		der, err := MarshalPKCS8PrivateKey(privateKey)
+		require.NoError(t, err)
+		parsedPrivateKey, err := ParsePKCS8PrivateKey(der)
+		require.NoError(t, err)
+		require.Equal(t, privateKey, parsedPrivateKey)
+
+		der, err = MarshalPKIXPublicKey(privateKey.Public())
+		require.NoError(t, err)
+		parsedPublicKey, err := ParsePKIXPublicKey(der)
+		require.NoError(t, err)
+		require.Equal(t, privateKey.Public(), parsedPublicKey)
+	}
+}
+
+func TestPKIXErrors(t *testing.T) {
+	privateKey := GenerateKeyFrom(reader)
+	curvePrivateKey, err := asn1.Marshal(privateKey.raw[:])
+	require.NoError(t, err)
+
+	// RFC 5958 version 2 with the public key
+	der, err := asn1.Marshal(pkcs8{
+		Version:    1,
+		Algorithm:  pkix.AlgorithmIdentifier{Algorithm: oidX25519},
+		PrivateKey: curvePrivateKey,
+		PublicKey:  asn1.BitString{Bytes: privateKey.Public()[:], BitLength: 256},
+	})
+	require.NoError(t, err)
+	parsedPrivateKey, err := ParsePKCS8PrivateKey(der)
+	require.NoError(t, err)
+	require.Equal(t, privateKey, parsedPrivateKey)
+
+	// Ed25519
+	der, err = asn1.Marshal(pkcs8{
+		Algorithm:  pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{1, 3, 101, 112}},
+		PrivateKey: curvePrivateKey,
+	})
+	require.NoError(t, err)
+	_, err = ParsePKCS8PrivateKey(der)
+	require.Error(t, err)
+
+	shortPrivateKey, err := asn1.Marshal(privateKey.raw[:31])
+	require.NoError(t, err)
+	der, err = asn1.Marshal(pkcs8{
+		Algorithm:  pkix.AlgorithmIdentifier{Algorithm: oidX25519},
+		PrivateKey: shortPrivateKey,
+	})
+	require.NoError(t, err)
+	_, err = ParsePKCS8PrivateKey(der)
+	require.Equal(t, ErrInvalidLength, err)
+
+	der, err = MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+	_, err = ParsePKCS8PrivateKey(append(der, 0))
+	require.Error(t, err)
+	_, err = ParsePKIXPublicKey(der)
+	require.Error(t, err)
+
+	der, err = asn1.Marshal(publicKeyInfo{
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidX25519},
+		PublicKey: asn1.BitString{Bytes: privateKey.Public()[:31], BitLength: 248},
+	})
+	require.NoError(t, err)
+	_, err = ParsePKIXPublicKey(der)
+	require.Equal(t, ErrInvalidLength, err)
+
+	der, err = MarshalPKIXPublicKey(privateKey.Public())
+	require.NoError(t, err)
+	_, err = ParsePKIXPublicKey(append(der, 0))
+	require.Error(t, err)
+	_, err = ParsePKCS8PrivateKey(der)
+	require.Error(t, err)
+}
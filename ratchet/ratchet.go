@@ -0,0 +1,403 @@
+// Package ratchet implements the Double Ratchet algorithm of Signal with
+// header encryption, on top of this module's X25519 keys.
+//
+// Both parties start from a 32-byte shared key agreed beforehand, e.g. with
+// X3DH.  The initiator also needs the responder's ratchet public key (in X3DH,
+// the signed prekey), and must send the first message.
+//
+// A message is an encrypted header of HeaderSize bytes followed by the
+// ciphertext.  The header, which holds the sender's ratchet public key and
+// message numbers, is encrypted with XChaCha20-Poly1305 under a header key
+// and a random nonce.  The ciphertext is ChaCha20-Poly1305 under a key and
+// nonce derived from the message key, authenticating the caller's associated
+// data followed by the encrypted header.
+package ratchet
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"io"
+
+	curve25519 "github.com/moonfruit/go-curve25519"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	// DefaultMaxSkip is the default of Session.MaxSkip.
+	DefaultMaxSkip = 1000
+	// DefaultMaxSkippedKeys is the default of Session.MaxSkippedKeys.
+	DefaultMaxSkippedKeys = 2000
+
+	/* ratchet public key, previous chain length and message number */
+	headerPlaintextSize = 32 + 4 + 4
+	// HeaderSize is the size of an encrypted header.
+	HeaderSize = chacha20poly1305.NonceSizeX + headerPlaintextSize + 16
+)
+
+var (
+	// ErrDecrypt is returned when a message fails authentication, or was
+	// already decrypted.
+	ErrDecrypt = errors.New("ratchet: message authentication failed")
+	// ErrTooManySkipped is returned when a message is further ahead than
+	// MaxSkip messages.
+	ErrTooManySkipped = errors.New("ratchet: too many skipped messages")
+	// ErrNotInitialized is returned when the responder encrypts before
+	// receiving a message.
+	ErrNotInitialized = errors.New("ratchet: no sending chain yet")
+)
+
+var (
+	rootInfo    = []byte("curve25519 ratchet root")
+	initInfo    = []byte("curve25519 ratchet init")
+	messageInfo = []byte("curve25519 ratchet message")
+)
+
+type skippedKey struct {
+	HeaderKey  []byte
+	N          uint32
+	MessageKey []byte
+}
+
+/* the state of the Double Ratchet specification, where nil keys are None */
+type state struct {
+	DHs       []byte /* private key */
+	DHr       []byte /* public key */
+	RK        []byte
+	CKs       []byte
+	CKr       []byte
+	HKs       []byte
+	HKr       []byte
+	NHKs      []byte
+	NHKr      []byte
+	Ns        uint32
+	Nr        uint32
+	PN        uint32
+	MKSkipped []skippedKey
+}
+
+// Session is one side of a Double Ratchet conversation.  It must not be used
+// concurrently, and must be persisted after each Encrypt and Decrypt.
+type Session struct {
+	state
+	// MaxSkip limits how far ahead of the next expected message a message
+	// may be in its chain, DefaultMaxSkip when zero.
+	MaxSkip int
+	// MaxSkippedKeys limits the number of message keys kept for messages not
+	// yet received, the oldest being dropped first.  DefaultMaxSkippedKeys
+	// when zero.
+	MaxSkippedKeys int
+	// Random is the source of ratchet keys and header nonces, crypto/rand
+	// when nil.
+	Random io.Reader
+}
+
+/* RK, HKa and NHKb for both parties, from the shared key */
+func initialKeys(sharedKey []byte) (rk, hka, nhkb []byte, err error) {
+	if len(sharedKey) != 32 {
+		return nil, nil, nil, errors.New("ratchet: shared key must be 32 bytes")
+	}
+	out := make([]byte, 96)
+	if _, err = io.ReadFull(hkdf.New(sha256.New, sharedKey, nil, initInfo), out); err != nil {
+		return nil, nil, nil, err
+	}
+	return out[:32], out[32:64], out[64:], nil
+}
+
+// NewInitiatorSession starts the session of the party that sends first, with
+// the responder's ratchet public key.
+func NewInitiatorSession(reader io.Reader, sharedKey []byte, responderKey *curve25519.PublicKey) (s *Session, err error) {
+	rk, hka, nhkb, err := initialKeys(sharedKey)
+	if err != nil {
+		return nil, err
+	}
+	s = &Session{Random: reader}
+	dhs, err := curve25519.GenerateKeyWithReader(s.random())
+	if err != nil {
+		return nil, err
+	}
+	s.DHs, s.DHr = dhs.Bytes(), append([]byte(nil), responderKey[:]...)
+	if s.RK, s.CKs, s.NHKs, err = kdfRK(rk, dhs, responderKey); err != nil {
+		return nil, err
+	}
+	s.HKs, s.NHKr = hka, nhkb
+	return
+}
+
+// NewResponderSession starts the session of the party that receives first,
+// with its ratchet key pair.
+func NewResponderSession(reader io.Reader, sharedKey []byte, ratchetKey *curve25519.PrivateKey) (s *Session, err error) {
+	rk, hka, nhkb, err := initialKeys(sharedKey)
+	if err != nil {
+		return nil, err
+	}
+	s = &Session{Random: reader}
+	s.DHs, s.RK = ratchetKey.Bytes(), rk
+	s.NHKs, s.NHKr = nhkb, hka
+	return
+}
+
+func (s *Session) random() io.Reader {
+	if s.Random == nil {
+		return rand.Reader
+	}
+	return s.Random
+}
+
+// Encrypt encrypts plaintext, authenticating ad as well, into the next
+// message.
+func (s *Session) Encrypt(plaintext, ad []byte) (message []byte, err error) {
+	if s.CKs == nil {
+		return nil, ErrNotInitialized
+	}
+	ck, mk := kdfCK(s.CKs)
+
+	header := make([]byte, headerPlaintextSize)
+	copy(header, curve25519.NewPrivateKey(s.DHs).Public()[:])
+	binary.BigEndian.PutUint32(header[32:], s.PN)
+	binary.BigEndian.PutUint32(header[36:], s.Ns)
+	nonce := make([]byte, chacha20poly1305.NonceSizeX)
+	if _, err = io.ReadFull(s.random(), nonce); err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.NewX(s.HKs)
+	if err != nil {
+		return nil, err
+	}
+	message = aead.Seal(nonce, nonce, header, nil)
+
+	if message, err = encrypt(message, mk, plaintext, ad); err != nil {
+		return nil, err
+	}
+	s.CKs = ck
+	s.Ns++
+	return
+}
+
+// Decrypt authenticates and decrypts message, with the same ad as given to
+// Encrypt.  Messages may arrive out of order, within the limits of MaxSkip
+// and MaxSkippedKeys.  The session is unchanged if it fails.
+func (s *Session) Decrypt(message, ad []byte) (plaintext []byte, err error) {
+	if len(message) < HeaderSize {
+		return nil, ErrDecrypt
+	}
+	encryptedHeader := message[:HeaderSize]
+
+	if plaintext, ok := s.trySkippedMessageKeys(encryptedHeader, message, ad); ok {
+		return plaintext, nil
+	}
+
+	/* work on a copy, so that a failure leaves the session untouched */
+	next := *s
+	next.MKSkipped = append([]skippedKey(nil), s.MKSkipped...)
+	header, dhRatchet, err := next.decryptHeader(encryptedHeader)
+	if err != nil {
+		return nil, err
+	}
+	pn, n := binary.BigEndian.Uint32(header[32:]), binary.BigEndian.Uint32(header[36:])
+	if dhRatchet {
+		if err = next.skipMessageKeys(pn); err != nil {
+			return nil, err
+		}
+		if err = next.dhRatchet(header[:32]); err != nil {
+			return nil, err
+		}
+	}
+	if err = next.skipMessageKeys(n); err != nil {
+		return nil, err
+	}
+	ck, mk := kdfCK(next.CKr)
+	if plaintext, err = decrypt(mk, message, ad); err != nil {
+		return nil, err
+	}
+	next.CKr = ck
+	next.Nr++
+	*s = next
+	return
+}
+
+func (s *Session) trySkippedMessageKeys(encryptedHeader, message, ad []byte) ([]byte, bool) {
+	for i, skipped := range s.MKSkipped {
+		header, ok := decryptHeader(skipped.HeaderKey, encryptedHeader)
+		if !ok || binary.BigEndian.Uint32(header[36:]) != skipped.N {
+			continue
+		}
+		plaintext, err := decrypt(skipped.MessageKey, message, ad)
+		if err != nil {
+			return nil, false
+		}
+		s.MKSkipped = append(s.MKSkipped[:i:i], s.MKSkipped[i+1:]...)
+		return plaintext, true
+	}
+	return nil, false
+}
+
+/* decrypts the header with HKr, or NHKr when it starts a new ratchet step */
+func (s *Session) decryptHeader(encryptedHeader []byte) (header []byte, dhRatchet bool, err error) {
+	if s.HKr != nil {
+		if header, ok := decryptHeader(s.HKr, encryptedHeader); ok {
+			return header, false, nil
+		}
+	}
+	if header, ok := decryptHeader(s.NHKr, encryptedHeader); ok {
+		return header, true, nil
+	}
+	return nil, false, ErrDecrypt
+}
+
+func (s *Session) skipMessageKeys(until uint32) error {
+	maxSkip, maxSkippedKeys := s.MaxSkip, s.MaxSkippedKeys
+	if maxSkip == 0 {
+		maxSkip = DefaultMaxSkip
+	}
+	if maxSkippedKeys == 0 {
+		maxSkippedKeys = DefaultMaxSkippedKeys
+	}
+	if uint64(s.Nr)+uint64(maxSkip) < uint64(until) {
+		return ErrTooManySkipped
+	}
+	if s.CKr == nil {
+		return nil
+	}
+	for s.Nr < until {
+		ck, mk := kdfCK(s.CKr)
+		s.MKSkipped = append(s.MKSkipped, skippedKey{s.HKr, s.Nr, mk})
+		s.CKr = ck
+		s.Nr++
+	}
+	if len(s.MKSkipped) > maxSkippedKeys {
+		s.MKSkipped = append([]skippedKey(nil), s.MKSkipped[len(s.MKSkipped)-maxSkippedKeys:]...)
+	}
+	return nil
+}
+
+func (s *Session) dhRatchet(dhr []byte) (err error) {
+	s.PN, s.Ns, s.Nr = s.Ns, 0, 0
+	s.HKs, s.HKr = s.NHKs, s.NHKr
+	s.DHr = append([]byte(nil), dhr...)
+	dhs := curve25519.NewPrivateKey(s.DHs)
+	peer := curve25519.NewPublicKey(s.DHr)
+	if s.RK, s.CKr, s.NHKr, err = kdfRK(s.RK, dhs, peer); err != nil {
+		return err
+	}
+	if dhs, err = curve25519.GenerateKeyWithReader(s.random()); err != nil {
+		return err
+	}
+	s.DHs = dhs.Bytes()
+	s.RK, s.CKs, s.NHKs, err = kdfRK(s.RK, dhs, peer)
+	return
+}
+
+/* KDF_RK_HE: HKDF-SHA256 with the root key as salt, giving the next root
+ * key, chain key and next header key */
+func kdfRK(rk []byte, sk *curve25519.PrivateKey, pk *curve25519.PublicKey) (nextRK, ck, nhk []byte, err error) {
+	dh, err := sk.SharedSecretChecked(pk)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	out := make([]byte, 96)
+	if _, err = io.ReadFull(hkdf.New(sha256.New, dh, rk, rootInfo), out); err != nil {
+		return nil, nil, nil, err
+	}
+	return out[:32], out[32:64], out[64:], nil
+}
+
+/* KDF_CK: HMAC-SHA256 of the chain key with 0x01 for the message key and
+ * 0x02 for the next chain key */
+func kdfCK(ck []byte) (nextCK, mk []byte) {
+	mac := hmac.New(sha256.New, ck)
+	mac.Write([]byte{1})
+	mk = mac.Sum(nil)
+	mac = hmac.New(sha256.New, ck)
+	mac.Write([]byte{2})
+	return mac.Sum(nil), mk
+}
+
+func decryptHeader(hk, encryptedHeader []byte) ([]byte, bool) {
+	aead, err := chacha20poly1305.NewX(hk)
+	if err != nil {
+		return nil, false
+	}
+	nonce := encryptedHeader[:chacha20poly1305.NonceSizeX]
+	header, err := aead.Open(nil, nonce, encryptedHeader[len(nonce):], nil)
+	return header, err == nil
+}
+
+/* the AEAD and nonce of a message key */
+func messageAEAD(mk []byte) (aead cipher.AEAD, nonce []byte, err error) {
+	out := make([]byte, chacha20poly1305.KeySize+chacha20poly1305.NonceSize)
+	if _, err = io.ReadFull(hkdf.New(sha256.New, mk, nil, messageInfo), out); err != nil {
+		return nil, nil, err
+	}
+	aead, err = chacha20poly1305.New(out[:chacha20poly1305.KeySize])
+	return aead, out[chacha20poly1305.KeySize:], err
+}
+
+/* appends the ciphertext to the encrypted header, authenticating
+ * ad || encrypted header */
+func encrypt(encryptedHeader, mk, plaintext, ad []byte) ([]byte, error) {
+	aead, nonce, err := messageAEAD(mk)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(encryptedHeader, nonce, plaintext, associatedData(ad, encryptedHeader)), nil
+}
+
+func decrypt(mk, message, ad []byte) ([]byte, error) {
+	aead, nonce, err := messageAEAD(mk)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, nonce, message[HeaderSize:], associatedData(ad, message[:HeaderSize]))
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+func associatedData(ad, encryptedHeader []byte) []byte {
+	var buf bytes.Buffer
+	buf.Write(ad)
+	buf.Write(encryptedHeader)
+	return buf.Bytes()
+}
+
+/* the serialized form of a session, after a version byte */
+const sessionVersion = 1
+
+type serializedSession struct {
+	State          state
+	MaxSkip        int
+	MaxSkippedKeys int
+}
+
+// MarshalBinary serializes the session, except for Random.  The result holds
+// secret keys and must be stored as such.
+func (s *Session) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte(sessionVersion)
+	if err := gob.NewEncoder(&buf).Encode(serializedSession{s.state, s.MaxSkip, s.MaxSkippedKeys}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary restores a session serialized by MarshalBinary.  Random is
+// left as it is.
+func (s *Session) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 || data[0] != sessionVersion {
+		return errors.New("ratchet: unsupported session version")
+	}
+	var serialized serializedSession
+	if err := gob.NewDecoder(bytes.NewReader(data[1:])).Decode(&serialized); err != nil {
+		return err
+	}
+	s.state, s.MaxSkip, s.MaxSkippedKeys = serialized.State, serialized.MaxSkip, serialized.MaxSkippedKeys
+	return nil
+}
@@ -0,0 +1,213 @@
+package ratchet
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	curve25519 "github.com/moonfruit/go-curve25519"
+	"github.com/stretchr/testify/require"
+)
+
+var reader = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+func newSessions(t *testing.T) (alice, bob *Session) {
+	sharedKey := make([]byte, 32)
+	reader.Read(sharedKey)
+	bobKey := curve25519.GenerateKeyFrom(reader)
+
+	alice, err := NewInitiatorSession(reader, sharedKey, bobKey.Public())
+	require.NoError(t, err)
+	bob, err = NewResponderSession(reader, sharedKey, bobKey)
+	require.NoError(t, err)
+	return
+}
+
+func send(t *testing.T, s *Session, plaintext string) []byte {
+	message, err := s.Encrypt([]byte(plaintext), []byte("ad"))
+	require.NoError(t, err)
+	require.Len(t, message, HeaderSize+len(plaintext)+16)
+	return message
+}
+
+func requireDecrypt(t *testing.T, s *Session, message []byte, plaintext string) {
+	decrypted, err := s.Decrypt(message, []byte("ad"))
+	require.NoError(t, err)
+	require.Equal(t, plaintext, string(decrypted))
+}
+
+func TestRatchet(t *testing.T) {
+	alice, bob := newSessions(t)
+
+	_, err := bob.Encrypt([]byte("too early"), nil)
+	require.Equal(t, ErrNotInitialized, err)
+
+	// several round trips, with several messages each way
+	for round := 0; round < 5; round++ {
+		for i := 0; i < 3; i++ {
+			plaintext := fmt.Sprintf("alice %d %d", round, i)
+			requireDecrypt(t, bob, send(t, alice, plaintext), plaintext)
+		}
+		for i := 0; i < 2; i++ {
+			plaintext := fmt.Sprintf("bob %d %d", round, i)
+			requireDecrypt(t, alice, send(t, bob, plaintext), plaintext)
+		}
+	}
+	require.Empty(t, alice.MKSkipped)
+	require.Empty(t, bob.MKSkipped)
+}
+
+func TestOutOfOrder(t *testing.T) {
+	alice, bob := newSessions(t)
+
+	var messages [][]byte
+	for i := 0; i < 5; i++ {
+		messages = append(messages, send(t, alice, fmt.Sprint("first ", i)))
+	}
+	requireDecrypt(t, bob, messages[3], "first 3")
+	requireDecrypt(t, bob, messages[0], "first 0")
+	require.Len(t, bob.MKSkipped, 2)
+
+	// bob replies, which ratchets, while messages of the first chain are
+	// still in flight
+	reply := send(t, bob, "reply")
+	requireDecrypt(t, alice, reply, "reply")
+	var second [][]byte
+	for i := 0; i < 3; i++ {
+		second = append(second, send(t, alice, fmt.Sprint("second ", i)))
+	}
+	requireDecrypt(t, bob, second[2], "second 2")
+	// the rest of the first chain was skipped as well
+	require.Len(t, bob.MKSkipped, 5)
+	requireDecrypt(t, bob, messages[4], "first 4")
+	requireDecrypt(t, bob, second[0], "second 0")
+	requireDecrypt(t, bob, messages[2], "first 2")
+	requireDecrypt(t, bob, second[1], "second 1")
+	requireDecrypt(t, bob, messages[1], "first 1")
+	require.Empty(t, bob.MKSkipped)
+
+	// replays
+	for _, message := range append(messages, second...) {
+		_, err := bob.Decrypt(message, []byte("ad"))
+		require.Equal(t, ErrDecrypt, err)
+	}
+
+	// random delivery order across several ratchet steps
+	type pending struct {
+		to        *Session
+		message   []byte
+		plaintext string
+	}
+	var inFlight []pending
+	for i := 0; i < 200; i++ {
+		from, to := alice, bob
+		if reader.Intn(3) == 0 {
+			from, to = bob, alice
+		}
+		plaintext := fmt.Sprint("message ", i)
+		inFlight = append(inFlight, pending{to, send(t, from, plaintext), plaintext})
+		if reader.Intn(4) == 0 {
+			j := reader.Intn(len(inFlight))
+			requireDecrypt(t, inFlight[j].to, inFlight[j].message, inFlight[j].plaintext)
+			inFlight = append(inFlight[:j], inFlight[j+1:]...)
+		}
+	}
+	reader.Shuffle(len(inFlight), func(i, j int) {
+		inFlight[i], inFlight[j] = inFlight[j], inFlight[i]
+	})
+	for _, p := range inFlight {
+		requireDecrypt(t, p.to, p.message, p.plaintext)
+	}
+	require.Empty(t, alice.MKSkipped)
+	require.Empty(t, bob.MKSkipped)
+}
+
+func TestLimits(t *testing.T) {
+	alice, bob := newSessions(t)
+	bob.MaxSkip = 10
+	bob.MaxSkippedKeys = 15
+
+	var messages [][]byte
+	for i := 0; i < 12; i++ {
+		messages = append(messages, send(t, alice, fmt.Sprint(i)))
+	}
+	_, err := bob.Decrypt(messages[11], []byte("ad"))
+	require.Equal(t, ErrTooManySkipped, err)
+	requireDecrypt(t, bob, messages[10], "10")
+	require.Len(t, bob.MKSkipped, 10)
+
+	// after the reply, another chain of skipped keys evicts the oldest
+	requireDecrypt(t, alice, send(t, bob, "reply"), "reply")
+	for i := 0; i < 11; i++ {
+		messages = append(messages, send(t, alice, fmt.Sprint(12+i)))
+	}
+	requireDecrypt(t, bob, messages[22], "22")
+	// messages[11] and the ten keys of the second chain, minus the oldest
+	require.Len(t, bob.MKSkipped, 15)
+	for i := 0; i < 6; i++ {
+		_, err = bob.Decrypt(messages[i], []byte("ad"))
+		require.Equal(t, ErrDecrypt, err)
+	}
+	for i := 6; i < 22; i++ {
+		if i == 10 {
+			continue
+		}
+		requireDecrypt(t, bob, messages[i], fmt.Sprint(i))
+	}
+}
+
+func TestTampering(t *testing.T) {
+	alice, bob := newSessions(t)
+	message := send(t, alice, "hello")
+
+	for _, i := range []int{0, 30, HeaderSize - 1, HeaderSize, len(message) - 1} {
+		tampered := append([]byte{}, message...)
+		tampered[i] ^= 1
+		_, err := bob.Decrypt(tampered, []byte("ad"))
+		require.Equal(t, ErrDecrypt, err)
+	}
+	_, err := bob.Decrypt(message, []byte("other"))
+	require.Equal(t, ErrDecrypt, err)
+	_, err = bob.Decrypt(message[:HeaderSize-1], []byte("ad"))
+	require.Equal(t, ErrDecrypt, err)
+
+	// failures left the session untouched
+	requireDecrypt(t, bob, message, "hello")
+}
+
+func TestSerialization(t *testing.T) {
+	alice, bob := newSessions(t)
+	bob.MaxSkip = 50
+
+	var messages [][]byte
+	for i := 0; i < 3; i++ {
+		messages = append(messages, send(t, alice, fmt.Sprint(i)))
+	}
+	requireDecrypt(t, bob, messages[2], "2")
+
+	data, err := bob.MarshalBinary()
+	require.NoError(t, err)
+	restored := &Session{Random: reader}
+	require.NoError(t, restored.UnmarshalBinary(data))
+	require.Equal(t, bob, restored)
+
+	requireDecrypt(t, restored, messages[0], "0")
+	requireDecrypt(t, alice, send(t, restored, "reply"), "reply")
+	requireDecrypt(t, restored, messages[1], "1")
+
+	data, err = alice.MarshalBinary()
+	require.NoError(t, err)
+	data[0] = 2
+	require.Error(t, restored.UnmarshalBinary(data))
+	require.Error(t, restored.UnmarshalBinary(nil))
+}
+
+func TestErrors(t *testing.T) {
+	_, err := NewInitiatorSession(reader, make([]byte, 31), curve25519.GenerateKeyFrom(reader).Public())
+	require.Error(t, err)
+	_, err = NewResponderSession(reader, make([]byte, 33), curve25519.GenerateKeyFrom(reader))
+	require.Error(t, err)
+	_, err = NewInitiatorSession(reader, make([]byte, 32), new(curve25519.PublicKey))
+	require.Equal(t, curve25519.ErrLowOrderPoint, err)
+}
@@ -0,0 +1,104 @@
+package curve25519
+
+import "crypto/subtle"
+
+// Scalar is an integer modulo the group order, stored as 32 little-endian
+// bytes.  The results of arithmetic are always fully reduced, but a Scalar
+// built with NewScalar holds its input as is until it is reduced.
+//
+// WARNING: the reduction and inversion helpers have data-dependent timing.
+type Scalar [32]byte
+
+// NewScalar returns a scalar holding the 32-byte little-endian input.
+func NewScalar(bytes []byte) (s *Scalar) {
+	s = new(Scalar)
+	copy(s[:], bytes)
+	return
+}
+
+// NewScalarWide returns the 64-byte little-endian input reduced modulo the
+// group order, which is suitable for turning hash outputs into scalars.
+func NewScalarWide(bytes []byte) (s *Scalar) {
+	wide := make([]byte, 64)
+	copy(wide, bytes)
+	s = new(Scalar)
+	reduce(s[:], wide)
+	return
+}
+
+// IsCanonical reports whether s is less than the group order.
+func (s *Scalar) IsCanonical() bool {
+	return isCanonicalScalar(s[:])
+}
+
+// Reduce sets s = x mod order and returns s.
+func (s *Scalar) Reduce(x *Scalar) *Scalar {
+	reduce(s[:], x[:])
+	return s
+}
+
+// Add sets s = x + y mod order and returns s.
+func (s *Scalar) Add(x, y *Scalar) *Scalar {
+	a := x.reduced()
+	b := y.reduced()
+	/* both are below order, so the sum can't overflow 32 bytes */
+	mulaSmall(a, a, 0, b, 32, 1)
+	reduce(s[:], a)
+	return s
+}
+
+// Sub sets s = x - y mod order and returns s.
+func (s *Scalar) Sub(x, y *Scalar) *Scalar {
+	a := x.reduced()
+	b := y.reduced()
+	/* a - b + order is positive, or wraps around to it if a - b was negative */
+	mulaSmall(a, a, 0, b, 32, -1)
+	mulaSmall(a, a, 0, order, 32, 1)
+	reduce(s[:], a)
+	return s
+}
+
+// Mul sets s = x * y mod order and returns s.
+func (s *Scalar) Mul(x, y *Scalar) *Scalar {
+	p := make([]byte, 64)
+	mula32(p, x.reduced(), y.reduced(), 32, 1)
+	reduce(s[:], p)
+	return s
+}
+
+// Negate sets s = -x mod order and returns s.
+func (s *Scalar) Negate(x *Scalar) *Scalar {
+	return s.Sub(new(Scalar), x)
+}
+
+// Invert sets s = 1/x mod order and returns s.  The inverse of zero is zero.
+func (s *Scalar) Invert(x *Scalar) *Scalar {
+	a := x.reduced()
+	b := append(order[:0:0], order...)
+	inv := egcd32(make([]byte, 64), make([]byte, 64), a, b)
+	if (inv[31] & 0x80) != 0 {
+		mulaSmall(inv, inv, 0, order, 32, 1)
+	}
+	reduce(s[:], inv[:32])
+	return s
+}
+
+// Equal reports whether s and t are congruent modulo the group order.
+func (s *Scalar) Equal(t *Scalar) bool {
+	return subtle.ConstantTimeCompare(s.reduced(), t.reduced()) == 1
+}
+
+func (s *Scalar) reduced() []byte {
+	r := make([]byte, 32)
+	reduce(r, s[:])
+	return r
+}
+
+/* r = x mod order
+ *   r  [out] 32 bytes
+ *   x  [in]  at least 32 bytes, left untouched unless it overlaps r */
+func reduce(r, x []byte) {
+	t := append(x[:0:0], x...)
+	divmod(make([]byte, len(t)-31), t, len(t), order, 32)
+	copy(r, t[:32])
+}
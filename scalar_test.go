@@ -0,0 +1,98 @@
+package curve25519
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var bigOrder = leToBig(order)
+
+func leToBig(b []byte) *big.Int {
+	be := make([]byte, len(b))
+	for i := range b {
+		be[len(b)-1-i] = b[i]
+	}
+	return new(big.Int).SetBytes(be)
+}
+
+func bigToScalar(x *big.Int) *Scalar {
+	be := x.Bytes()
+	s := new(Scalar)
+	for i := range be {
+		s[len(be)-1-i] = be[i]
+	}
+	return s
+}
+
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	reader.Read(b)
+	return b
+}
+
+func TestScalar(t *testing.T) {
+	for i := 0; i < 10000; i++ {
+		xBytes := randomBytes(32)
+		yBytes := randomBytes(32)
+		x := NewScalar(xBytes)
+		y := NewScalar(yBytes)
+		bx := new(big.Int).Mod(leToBig(xBytes), bigOrder)
+		by := new(big.Int).Mod(leToBig(yBytes), bigOrder)
+
+		require.Equal(t, bigToScalar(bx), new(Scalar).Reduce(x))
+		require.Equal(t, leToBig(xBytes).Cmp(bigOrder) < 0, x.IsCanonical())
+
+		expected := new(big.Int).Add(bx, by)
+		require.Equal(t, bigToScalar(expected.Mod(expected, bigOrder)), new(Scalar).Add(x, y))
+
+		expected.Sub(bx, by)
+		require.Equal(t, bigToScalar(expected.Mod(expected, bigOrder)), new(Scalar).Sub(x, y))
+
+		expected.Mul(bx, by)
+		require.Equal(t, bigToScalar(expected.Mod(expected, bigOrder)), new(Scalar).Mul(x, y))
+
+		expected.Neg(bx)
+		require.Equal(t, bigToScalar(expected.Mod(expected, bigOrder)), new(Scalar).Negate(x))
+
+		expected.ModInverse(bx, bigOrder)
+		require.Equal(t, bigToScalar(expected), new(Scalar).Invert(x))
+
+		wide := randomBytes(64)
+		expected.Mod(leToBig(wide), bigOrder)
+		require.Equal(t, bigToScalar(expected), NewScalarWide(wide))
+
+		require.True(t, x.Equal(bigToScalar(bx)))
+		require.False(t, x.Equal(new(Scalar).Add(x, NewScalar([]byte{1}))))
+	}
+}
+
+func TestScalarEdgeCases(t *testing.T) {
+	l := NewScalar(order)
+	require.False(t, l.IsCanonical())
+	require.Equal(t, new(Scalar), new(Scalar).Reduce(l))
+	require.True(t, l.Equal(new(Scalar)))
+
+	lMinusOne := new(Scalar).Sub(new(Scalar), NewScalar([]byte{1}))
+	require.True(t, lMinusOne.IsCanonical())
+	require.Equal(t, new(Scalar), new(Scalar).Add(lMinusOne, NewScalar([]byte{1})))
+	require.Equal(t, NewScalar([]byte{1}), new(Scalar).Mul(lMinusOne, lMinusOne))
+	require.Equal(t, lMinusOne, new(Scalar).Invert(lMinusOne))
+
+	require.Equal(t, new(Scalar), new(Scalar).Invert(new(Scalar)))
+
+	max := NewScalarWide([]byte{
+		255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255,
+		255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255,
+		255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255,
+		255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255,
+	})
+	expected := new(big.Int).Lsh(big.NewInt(1), 512)
+	expected.Sub(expected, big.NewInt(1))
+	require.Equal(t, bigToScalar(expected.Mod(expected, bigOrder)), max)
+
+	x := NewScalar(randomBytes(32))
+	double := new(Scalar).Add(x, x)
+	require.Equal(t, double, x.Add(x, x))
+}
@@ -0,0 +1,71 @@
+package curve25519
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"errors"
+	"hash"
+)
+
+// Signer is an io.Writer that signs everything written to it, without
+// keeping the message in memory.  The signature is the same as the one Sign
+// would produce for the concatenation of all writes.
+type Signer struct {
+	sk   *PrivateKey
+	hash hash.Hash
+}
+
+func NewSigner(sk *PrivateKey) *Signer {
+	return &Signer{sk: sk, hash: sha256.New()}
+}
+
+func (s *Signer) Write(p []byte) (int, error) {
+	return s.hash.Write(p)
+}
+
+// Sign returns the signature of the message written so far.  It doesn't
+// change the state, so more data may be written and signed afterwards.
+func (s *Signer) Sign() *Signature {
+	return s.sk.signDigest(crypto.SHA256, s.hash.Sum(nil))
+}
+
+// Verifier is an io.Writer that checks a signature against everything
+// written to it, with the same result as Verify on the concatenation of all
+// writes.
+type Verifier struct {
+	pk        *PublicKey
+	signature *Signature
+	hash      hash.Hash
+}
+
+func NewVerifier(pk *PublicKey, signature *Signature) *Verifier {
+	return &Verifier{pk: pk, signature: signature, hash: sha256.New()}
+}
+
+func (v *Verifier) Write(p []byte) (int, error) {
+	return v.hash.Write(p)
+}
+
+// Verify reports whether the signature is valid for the message written so
+// far.
+func (v *Verifier) Verify(enforceCanonical bool) bool {
+	return verifyDigest(crypto.SHA256, v.hash.Sum(nil), v.signature, v.pk, enforceCanonical)
+}
+
+// SignDigest signs a message given its SHA-256 digest, with the same result
+// as Sign on the message itself.
+func (sk *PrivateKey) SignDigest(messageDigest []byte) (*Signature, error) {
+	if len(messageDigest) != sha256.Size {
+		return nil, errors.New("curve25519: message digest must be 32 bytes")
+	}
+	return sk.signDigest(crypto.SHA256, messageDigest), nil
+}
+
+// VerifyDigest verifies a signature given the SHA-256 digest of the message,
+// with the same result as Verify on the message itself.
+func VerifyDigest(messageDigest []byte, signature *Signature, pk *PublicKey, enforceCanonical bool) bool {
+	if len(messageDigest) != sha256.Size {
+		return false
+	}
+	return verifyDigest(crypto.SHA256, messageDigest, signature, pk, enforceCanonical)
+}
@@ -0,0 +1,48 @@
+package curve25519
+
+import (
+	"crypto/sha256"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStream(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		privateKey := GenerateKeyFrom(reader)
+		publicKey := privateKey.Public()
+		message := randomBytes(reader.Intn(1 << 16))
+		expected := privateKey.Sign(message)
+
+		signer := NewSigner(privateKey)
+		verifier := NewVerifier(publicKey, expected)
+		for rest := message; len(rest) > 0; {
+			n := reader.Intn(len(rest) + 1)
+			_, err := io.Writer(signer).Write(rest[:n])
+			require.NoError(t, err)
+			_, err = io.Writer(verifier).Write(rest[:n])
+			require.NoError(t, err)
+			rest = rest[n:]
+		}
+		require.Equal(t, expected, signer.Sign())
+		require.True(t, verifier.Verify(true))
+
+		signer.Write([]byte{0})
+		verifier.Write([]byte{0})
+		require.Equal(t, privateKey.Sign(append(message, 0)), signer.Sign())
+		require.False(t, verifier.Verify(true))
+
+		digest := sha256.Sum256(message)
+		signature, err := privateKey.SignDigest(digest[:])
+		require.NoError(t, err)
+		require.Equal(t, expected, signature)
+		require.True(t, VerifyDigest(digest[:], expected, publicKey, true))
+		require.False(t, VerifyDigest(digest[:], expected, GenerateKeyFrom(reader).Public(), true))
+	}
+
+	privateKey := GenerateKeyFrom(reader)
+	_, err := privateKey.SignDigest(make([]byte, 31))
+	require.Error(t, err)
+	require.False(t, VerifyDigest(make([]byte, 64), privateKey.Sign(nil), privateKey.Public(), true))
+}
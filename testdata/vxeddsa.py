@@ -0,0 +1,203 @@
+#!/usr/bin/env python3
+"""Writes the vectors of vxeddsa.txt.
+
+A plain implementation of VXEdDSA as given in "The XEdDSA and VEdDSA
+Signature Schemes" (Signal, revision 1, 2016-10-20), sections 2.5, 2.6 and
+5, using only the Python standard library and sharing no code with the Go
+package.  Each line holds the private key, the message, the 64 bytes of Z,
+the proof and the VRF output, in hex.
+
+    python3 testdata/vxeddsa.py > testdata/vxeddsa.txt
+"""
+
+import hashlib
+
+p = 2**255 - 19
+q = 2**252 + 27742317777372353535851937790883648493
+d = -121665 * pow(121666, p - 2, p) % p
+sqrt_m1 = pow(2, (p - 1) // 4, p)
+
+
+def inv(x):
+    return pow(x, p - 2, p)
+
+
+def is_square(x):
+    return x % p == 0 or pow(x, (p - 1) // 2, p) == 1
+
+
+def sqrt(x):
+    y = pow(x, (p + 3) // 8, p)
+    if (y * y - x) % p != 0:
+        y = y * sqrt_m1 % p
+    assert (y * y - x) % p == 0
+    return y
+
+
+# Points are affine (x, y) on -x^2 + y^2 = 1 + d x^2 y^2.
+
+identity = (0, 1)
+
+
+def add(P, Q):
+    x1, y1 = P
+    x2, y2 = Q
+    t = d * x1 * x2 * y1 * y2 % p
+    return ((x1 * y2 + x2 * y1) * inv(1 + t) % p,
+            (y1 * y2 + x1 * x2) * inv(1 - t) % p)
+
+
+def mul(n, P):
+    R = identity
+    while n:
+        if n & 1:
+            R = add(R, P)
+        P = add(P, P)
+        n >>= 1
+    return R
+
+
+def neg(P):
+    return (-P[0] % p, P[1])
+
+
+def encode(P):
+    x, y = P
+    return (y | (x & 1) << 255).to_bytes(32, 'little')
+
+
+def decode(b):
+    n = int.from_bytes(b, 'little')
+    y, sign = n & (2**255 - 1), n >> 255
+    if y >= p:
+        return None
+    xx = (y * y - 1) * inv(d * y * y + 1) % p
+    if not is_square(xx):
+        return None
+    x = sqrt(xx)
+    if x == 0 and sign:
+        return None
+    if x & 1 != sign:
+        x = p - x
+    return (x, y)
+
+
+B = decode((4 * inv(5) % p).to_bytes(32, 'little'))
+
+
+def hash_i(i, *parts):
+    h = hashlib.sha512((2**256 - 1 - i).to_bytes(32, 'little'))
+    for part in parts:
+        h.update(part)
+    return h.digest()
+
+
+def scalar(b):
+    return int.from_bytes(b, 'little') % q
+
+
+# section 2.3, the private key k is clamped
+def clamp(k):
+    k = bytearray(k)
+    k[0] &= 248
+    k[31] &= 127
+    k[31] |= 64
+    return int.from_bytes(k, 'little')
+
+
+# section 5.3, calculate_key_pair
+def calculate_key_pair(k):
+    E = mul(k, B)
+    a = k % q
+    if E[0] & 1:
+        E, a = neg(E), -a % q
+    return encode(E), a
+
+
+# section 2.6, elligator2
+def elligator2(r):
+    A = 486662
+    x = -A * inv(1 + 2 * r * r) % p
+    if is_square(x**3 + A * x * x + x):
+        return x
+    return (-x - A) % p
+
+
+# section 5.1, convert_mont, with the sign given
+def u_to_y(u):
+    return (u - 1) * inv(u + 1) % p
+
+
+def from_y(y, sign):
+    return decode((y | sign << 255).to_bytes(32, 'little'))
+
+
+# section 2.5, hash_to_point
+def hash_to_point(X):
+    h = hash_i(2, X)
+    n = int.from_bytes(h[:32], 'little')
+    r = n & (2**255 - 1)
+    sign = n >> 255
+    u = elligator2(r % p)
+    P = from_y(u_to_y(u), sign)
+    if P is None:
+        P = from_y(u_to_y(u), 0)
+    return mul(8, P)
+
+
+# section 5, vxeddsa_sign
+def vxeddsa_sign(k, M, Z):
+    A, a = calculate_key_pair(clamp(k))
+    Bv = hash_to_point(A + M)
+    V = encode(mul(a, Bv))
+    r = scalar(hash_i(3, a.to_bytes(32, 'little'), V, Z))
+    R = encode(mul(r, B))
+    Rv = encode(mul(r, Bv))
+    h = scalar(hash_i(4, A, V, R, Rv, M))
+    s = (r + h * a) % q
+    v = hash_i(5, encode(mul(8, decode(V))))[:32]
+    return V + h.to_bytes(32, 'little') + s.to_bytes(32, 'little'), v
+
+
+# section 5, vxeddsa_verify
+def vxeddsa_verify(u, M, proof):
+    A = from_y(u_to_y(int.from_bytes(u, 'little') % p), 0)
+    Bv = hash_to_point(encode(A) + M)
+    V = decode(proof[:32])
+    h = int.from_bytes(proof[32:64], 'little')
+    s = int.from_bytes(proof[64:], 'little')
+    if V is None or h >= 2**253 or s >= 2**253:
+        return None
+    if identity in (mul(8, A), mul(8, V), Bv):
+        return None
+    R = add(mul(s, B), neg(mul(h, A)))
+    Rv = add(mul(s, Bv), neg(mul(h, V)))
+    if h != scalar(hash_i(4, encode(A), proof[:32], encode(R), encode(Rv), M)):
+        return None
+    return hash_i(5, encode(mul(8, V)))[:32]
+
+
+def montgomery_public(k):
+    x, y = mul(clamp(k), B)
+    return ((1 + y) * inv(1 - y) % p).to_bytes(32, 'little')
+
+
+def main():
+    # deterministic inputs, so that the file is reproducible
+    stream = hashlib.shake_256(b'go-curve25519 VXEdDSA vectors').digest(64 * 200)
+    offset = 0
+
+    def take(n):
+        nonlocal offset
+        offset += n
+        return stream[offset - n:offset]
+
+    for length in [1, 5, 32, 33, 64, 100, 255]:
+        k, Z, M = take(32), take(64), take(length)
+        proof, v = vxeddsa_sign(k, M, Z)
+        assert vxeddsa_verify(montgomery_public(k), M, proof) == v
+        print(k.hex(), M.hex(), Z.hex(), proof.hex(), v.hex())
+
+
+if __name__ == '__main__':
+    main()
@@ -0,0 +1,139 @@
+package curve25519
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var textEncodings = []*base64.Encoding{
+	base64.StdEncoding.Strict(),
+	base64.RawStdEncoding.Strict(),
+	base64.URLEncoding.Strict(),
+	base64.RawURLEncoding.Strict(),
+}
+
+// String returns pk in hex.
+func (pk PublicKey) String() string {
+	return hex.EncodeToString(pk[:])
+}
+
+// Format formats pk with String for the %v, %s and %q verbs, and as the bytes
+// of the key for the others, so that %x still gives the key in hex rather
+// than the hex of String.
+func (pk PublicKey) Format(f fmt.State, verb rune) {
+	formatBytes(f, verb, "curve25519.PublicKey", [32]byte(pk), pk.String())
+}
+
+// MarshalText returns pk in hex, so that it can be used in JSON and other
+// text formats.
+func (pk PublicKey) MarshalText() ([]byte, error) {
+	return []byte(pk.String()), nil
+}
+
+// UnmarshalText sets pk to the key in text, which is either hex or base64
+// (standard or URL-safe, with or without padding).
+func (pk *PublicKey) UnmarshalText(text []byte) error {
+	return decodeText(pk[:], text)
+}
+
+// Set is UnmarshalText for flag.Value.
+func (pk *PublicKey) Set(value string) error {
+	return pk.UnmarshalText([]byte(value))
+}
+
+// String returns s in hex.
+func (s Signature) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// Format formats s with String for the %v, %s and %q verbs, and as the bytes
+// of the signature for the others, as PublicKey does.
+func (s Signature) Format(f fmt.State, verb rune) {
+	formatBytes(f, verb, "curve25519.Signature", [64]byte(s), s.String())
+}
+
+// MarshalText returns s in hex, so that it can be used in JSON and other text
+// formats.
+func (s Signature) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText sets s to the signature in text, which is either hex or
+// base64 (standard or URL-safe, with or without padding).
+func (s *Signature) UnmarshalText(text []byte) error {
+	return decodeText(s[:], text)
+}
+
+// Set is UnmarshalText for flag.Value.
+func (s *Signature) Set(value string) error {
+	return s.UnmarshalText([]byte(value))
+}
+
+// String returns a placeholder rather than the key, so that private keys don't
+// end up in logs.  Use Bytes to get the key itself.
+func (sk PrivateKey) String() string {
+	return "curve25519.PrivateKey{REDACTED}"
+}
+
+// GoString is String for the %#v verb.
+func (sk PrivateKey) GoString() string {
+	return sk.String()
+}
+
+/* formats the bytes b of the type name as fmt would without String, but for
+ * the verbs of fmt.Stringer, which get s */
+func formatBytes(f fmt.State, verb rune, name string, b interface{}, s string) {
+	directive := "%"
+	for _, flag := range "+-# 0" {
+		if f.Flag(int(flag)) {
+			directive += string(flag)
+		}
+	}
+	if width, ok := f.Width(); ok {
+		directive += strconv.Itoa(width)
+	}
+	if precision, ok := f.Precision(); ok {
+		directive += "." + strconv.Itoa(precision)
+	}
+	directive += string(verb)
+
+	switch {
+	case verb == 'v' && f.Flag('#'):
+		goSyntax := fmt.Sprintf("%#v", b)
+		fmt.Fprint(f, name+goSyntax[strings.IndexByte(goSyntax, '{'):])
+	case verb == 'v' || verb == 's' || verb == 'q':
+		fmt.Fprintf(f, directive, s)
+	default:
+		fmt.Fprintf(f, directive, b)
+	}
+}
+
+/* decodes hex or base64 text into dst, which must be filled exactly.  The
+ * lengths of the two encodings never coincide, so hex is tried only when the
+ * length matches it */
+func decodeText(dst, text []byte) error {
+	var decoded []byte
+	var err error
+	if len(text) == hex.EncodedLen(len(dst)) {
+		decoded = make([]byte, len(dst))
+		_, err = hex.Decode(decoded, text)
+	} else {
+		for _, encoding := range textEncodings {
+			if decoded, err = encoding.DecodeString(string(text)); err == nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return errors.New("curve25519: invalid hex or base64 encoding")
+	}
+	if len(decoded) != len(dst) {
+		return ErrInvalidLength
+	}
+	copy(dst, decoded)
+	return nil
+}
@@ -0,0 +1,93 @@
+package curve25519
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestText(t *testing.T) {
+	privateKey := GenerateKeyFrom(reader)
+	publicKey := privateKey.Public()
+	signature := privateKey.Sign([]byte("message"))
+
+	require.Equal(t, hex.EncodeToString(publicKey[:]), publicKey.String())
+	require.Equal(t, hex.EncodeToString(publicKey[:]), fmt.Sprint(publicKey))
+	require.Equal(t, hex.EncodeToString(signature[:]), signature.String())
+	require.Equal(t, hex.EncodeToString(signature[:]), fmt.Sprint(*signature))
+
+	// the other verbs format the bytes, as they did before String
+	for _, format := range []string{"%x", "%X", "% x", "%#x", "%d", "%80x"} {
+		require.Equal(t, fmt.Sprintf(format, [32]byte(*publicKey)), fmt.Sprintf(format, publicKey), format)
+		require.Equal(t, fmt.Sprintf(format, [64]byte(*signature)), fmt.Sprintf(format, *signature), format)
+	}
+	require.Equal(t, hex.EncodeToString(publicKey[:]), fmt.Sprintf("%x", publicKey))
+	require.Equal(t, `"`+publicKey.String()+`"`, fmt.Sprintf("%q", publicKey))
+	require.Equal(t, "curve25519.PublicKey"+strings.TrimPrefix(fmt.Sprintf("%#v", [32]byte(*publicKey)), "[32]uint8"), fmt.Sprintf("%#v", *publicKey))
+	require.Equal(t, "curve25519.Signature"+strings.TrimPrefix(fmt.Sprintf("%#v", [64]byte(*signature)), "[64]uint8"), fmt.Sprintf("%#v", *signature))
+
+	for _, encode := range []func([]byte) string{
+		hex.EncodeToString,
+		base64.StdEncoding.EncodeToString,
+		base64.RawStdEncoding.EncodeToString,
+		base64.URLEncoding.EncodeToString,
+		base64.RawURLEncoding.EncodeToString,
+	} {
+		var decodedPublicKey PublicKey
+		require.NoError(t, decodedPublicKey.UnmarshalText([]byte(encode(publicKey[:]))))
+		require.Equal(t, publicKey, &decodedPublicKey)
+		var decodedSignature Signature
+		require.NoError(t, decodedSignature.UnmarshalText([]byte(encode(signature[:]))))
+		require.Equal(t, signature, &decodedSignature)
+
+		require.Equal(t, ErrInvalidLength, decodedPublicKey.UnmarshalText([]byte(encode(signature[:]))))
+		require.Equal(t, ErrInvalidLength, decodedSignature.UnmarshalText([]byte(encode(publicKey[:]))))
+	}
+
+	var decodedPublicKey PublicKey
+	for _, text := range []string{
+		"",
+		"zz" + publicKey.String()[2:],
+		base64.StdEncoding.EncodeToString(publicKey[:]) + "=",
+		"!" + base64.RawStdEncoding.EncodeToString(publicKey[:])[1:],
+	} {
+		require.Error(t, decodedPublicKey.UnmarshalText([]byte(text)), text)
+	}
+
+	type keys struct {
+		PublicKey PublicKey
+		Signature *Signature
+	}
+	data, err := json.Marshal(keys{*publicKey, signature})
+	require.NoError(t, err)
+	require.Equal(t, `{"PublicKey":"`+publicKey.String()+`","Signature":"`+signature.String()+`"}`, string(data))
+	var decodedKeys keys
+	require.NoError(t, json.Unmarshal(data, &decodedKeys))
+	require.Equal(t, keys{*publicKey, signature}, decodedKeys)
+
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.Var(&decodedKeys.PublicKey, "public-key", "")
+	flags.Var(decodedKeys.Signature, "signature", "")
+	otherPrivateKey := GenerateKeyFrom(reader)
+	require.NoError(t, flags.Parse([]string{
+		"-public-key", base64.StdEncoding.EncodeToString(otherPrivateKey.Public()[:]),
+		"-signature", otherPrivateKey.Sign([]byte("message")).String(),
+	}))
+	require.Equal(t, otherPrivateKey.Public(), &decodedKeys.PublicKey)
+	require.Equal(t, otherPrivateKey.Sign([]byte("message")), decodedKeys.Signature)
+
+	for _, format := range []string{"%v", "%+v", "%#v", "%s", "%x"} {
+		for _, v := range []interface{}{privateKey, *privateKey} {
+			s := fmt.Sprintf(format, v)
+			require.NotContains(t, s, hex.EncodeToString(privateKey.raw[:]), format)
+		}
+	}
+	require.Equal(t, "curve25519.PrivateKey{REDACTED}", fmt.Sprint(privateKey))
+	require.Equal(t, "curve25519.PrivateKey{REDACTED}", fmt.Sprintf("%#v", privateKey))
+}
@@ -0,0 +1,158 @@
+package curve25519
+
+import "crypto/subtle"
+
+/* VXEdDSA, the verifiable random function from the XEdDSA specification
+ *
+ * proving:
+ *
+ *    A, a = calculate_key_pair(k)
+ *    Bv = hash_to_point(A || M)
+ *    V = aBv
+ *    r = hash3(a || V || Z) (mod q)
+ *    R = rB
+ *    Rv = rBv
+ *    h = hash4(A || V || R || Rv || M) (mod q)
+ *    s = r + ha (mod q)
+ *    v = hash5(cV) (mod 2^b)
+ *
+ *    output (V,h,s) as the proof and v as the VRF output
+ *
+ * verification:
+ *
+ *    A = convert_mont(u)
+ *    Bv = hash_to_point(A || M)
+ *    confirm  cA, cV and cBv aren't the identity
+ *    R = sB - hA
+ *    Rv = sBv - hV
+ *    confirm  h == hash4(A || V || R || Rv || M) (mod q)
+ *
+ *    output v = hash5(cV) (mod 2^b)
+ */
+
+/* Elligator 2, maps r to the u-coordinate of a point on Curve25519
+ *   x = -A / (1 + 2 r^2)
+ *   u = x       if x^3 + A x^2 + x is a square (or zero)
+ *   u = -x - A  otherwise
+ * WARNING: this function has data-dependent timing */
+func elligator2(r *FieldElement) *FieldElement {
+	A := feFromInt(486662)
+	one := new(FieldElement).One()
+
+	x := new(FieldElement).Square(r)
+	x.Add(x, x)
+	x.Add(x, one)
+	x.Invert(x)
+	x.Mul(x, new(FieldElement).Negate(A))
+
+	rhs := new(FieldElement).Add(x, A)
+	rhs.Mul(rhs, x)
+	rhs.Add(rhs, one)
+	rhs.Mul(rhs, x)
+	if _, square := new(FieldElement).Sqrt(rhs); !square {
+		x.Negate(x)
+		x.Sub(x, A)
+	}
+	return x
+}
+
+/* hash_to_point: the low 255 bits of hash2(X) are mapped to a u-coordinate
+ * with Elligator 2, the top bit picks the sign of the Edwards point, and the
+ * result is multiplied by the cofactor.  The exceptional u = -1 has no
+ * Edwards equivalent and maps to the identity, which verification rejects.
+ * WARNING: this function has data-dependent timing */
+func hashToPoint(X ...[]byte) *edPoint {
+	h := hashI(2, X...)
+	sign := int(h[31] >> 7)
+	h[31] &= 0x7F
+	r, _ := new(FieldElement).SetBytes(h[:32])
+	u := elligator2(r).Bytes()
+
+	P, ok := convertMont(u, sign)
+	if !ok {
+		/* x = 0 has no negative, so both signs give the same point */
+		if P, ok = convertMont(u, 0); !ok {
+			return new(edPoint).identity()
+		}
+	}
+	return P.mulByCofactor(P)
+}
+
+/* VXEdDSA proof generation
+ *   proof  [out] 96-byte proof
+ *   v      [out] 32-byte VRF output
+ *   k      [in]  Montgomery private key
+ *   M      [in]  message
+ *   Z      [in]  64 bytes of secure random data
+ */
+func vxeddsaSign(proof, v, k, M, Z []byte) {
+	A, a := calculateKeyPair(k)
+	Bv := hashToPoint(A, M)
+	V := new(edPoint).scalarMult(a[:], Bv)
+	VBytes := V.bytes()
+
+	r := NewScalarWide(hashI(3, a[:], VBytes, Z))
+	R := new(edPoint).scalarBaseMult(r[:])
+	Rv := new(edPoint).scalarMult(r[:], Bv)
+	h := NewScalarWide(hashI(4, A, VBytes, R.bytes(), Rv.bytes(), M))
+
+	s := new(Scalar).Mul(h, a)
+	s.Add(s, r)
+
+	copy(proof, VBytes)
+	copy(proof[32:], h[:])
+	copy(proof[64:], s[:])
+	copy(v, hashI(5, V.mulByCofactor(V).bytes()))
+}
+
+/* VXEdDSA proof verification, returns true and fills in v on success
+ *   v      [out] 32-byte VRF output
+ *   u      [in]  Montgomery public key
+ *   M      [in]  message
+ *   proof  [in]  96-byte proof
+ */
+func vxeddsaVerify(v, u, M, proof []byte) bool {
+	if len(proof) != 96 {
+		return false
+	}
+	VBytes := proof[:32]
+	h := proof[32:64]
+	s := proof[64:]
+	if (h[31]&0xE0) != 0 || (s[31]&0xE0) != 0 {
+		return false
+	}
+
+	A, ok := convertMont(u, 0)
+	if !ok {
+		return false
+	}
+	ABytes := A.bytes()
+	Bv := hashToPoint(ABytes, M)
+	V, ok := new(edPoint).setBytes(VBytes)
+	if !ok {
+		return false
+	}
+
+	cV := new(edPoint).mulByCofactor(V)
+	if new(edPoint).mulByCofactor(A).isIdentity() || cV.isIdentity() || Bv.isIdentity() {
+		return false
+	}
+
+	/* R = sB - hA */
+	R := new(edPoint).scalarMult(h, A)
+	R.negate(R)
+	R.add(R, new(edPoint).scalarBaseMult(s))
+
+	/* Rv = sBv - hV */
+	Rv := new(edPoint).scalarMult(h, V)
+	Rv.negate(Rv)
+	Rv.add(Rv, new(edPoint).scalarMult(s, Bv))
+
+	check := NewScalarWide(hashI(4, ABytes, VBytes, R.bytes(), Rv.bytes(), M))
+	if subtle.ConstantTimeCompare(check[:], h) != 1 {
+		return false
+	}
+
+	copy(v, hashI(5, cV.bytes()))
+	return true
+}
@@ -0,0 +1,97 @@
+package curve25519
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVRFVectors(t *testing.T) {
+	// Computed by testdata/vxeddsa.py, a separate Python implementation of
+	// the XEdDSA specification, with the Z each proof was made with.
+	file, err := os.Open(filepath.Join("testdata", "vxeddsa.txt"))
+	require.NoError(t, err)
+	defer file.Close()
+
+	for {
+		var hexPrivateKey, hexMessage, hexRandom, hexProof, hexOutput string
+		n, err := fmt.Fscanln(file, &hexPrivateKey, &hexMessage, &hexRandom, &hexProof, &hexOutput)
+		if n == 0 && err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		privateKey := NewPrivateKey(decodeHex(t, hexPrivateKey))
+		message := decodeHex(t, hexMessage)
+		random := decodeHex(t, hexRandom)
+		proof := NewVRFProof(decodeHex(t, hexProof))
+		expected := decodeHex(t, hexOutput)
+
+		output, ok := VerifyVRF(privateKey.Public(), message, proof)
+		require.True(t, ok)
+		require.Equal(t, expected, output)
+
+		output, another, err := privateKey.Prove(bytes.NewReader(random), message)
+		require.NoError(t, err)
+		require.Equal(t, expected, output)
+		require.Equal(t, proof, another)
+
+		// The proof is randomized, but the output isn't.
+		output, another, err = privateKey.Prove(reader, message)
+		require.NoError(t, err)
+		require.Equal(t, expected, output)
+		require.NotEqual(t, proof, another)
+	}
+}
+
+func TestVRF(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		privateKey := GenerateKeyFrom(reader)
+		publicKey := privateKey.Public()
+		message := randomBytes(i)
+
+		output, proof, err := privateKey.Prove(reader, message)
+		require.NoError(t, err)
+		require.Len(t, output, 32)
+
+		verified, ok := VerifyVRF(publicKey, message, proof)
+		require.True(t, ok)
+		require.Equal(t, output, verified)
+
+		other, _, err := privateKey.Prove(reader, append(message, 0))
+		require.NoError(t, err)
+		require.NotEqual(t, output, other)
+
+		_, ok = VerifyVRF(GenerateKeyFrom(reader).Public(), message, proof)
+		require.False(t, ok)
+		_, ok = VerifyVRF(publicKey, append(message, 0), proof)
+		require.False(t, ok)
+
+		for _, j := range []int{0, 32, 64} {
+			tampered := *proof
+			tampered[j] ^= 1
+			_, ok = VerifyVRF(publicKey, message, &tampered)
+			require.False(t, ok)
+		}
+	}
+
+	_, _, err := GenerateKeyFrom(reader).Prove(io.LimitReader(reader, 63), nil)
+	require.Error(t, err)
+}
+
+func TestVRFRejectsIdentity(t *testing.T) {
+	privateKey := GenerateKeyFrom(reader)
+	_, proof, err := privateKey.Prove(reader, nil)
+	require.NoError(t, err)
+
+	// V is replaced with the identity point.
+	tampered := *proof
+	copy(tampered[:32], new(edPoint).identity().bytes())
+	_, ok := VerifyVRF(privateKey.Public(), nil, &tampered)
+	require.False(t, ok)
+}
@@ -0,0 +1,98 @@
+package curve25519
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// PresharedKey is a WireGuard preshared key, a symmetric key mixed into the
+// handshake of two peers.  The zero key is used when none is configured.
+type PresharedKey [32]byte
+
+// GeneratePresharedKey reads a preshared key from reader, as wg genpsk does.
+func GeneratePresharedKey(reader io.Reader) (psk *PresharedKey, err error) {
+	psk = new(PresharedKey)
+	if _, err = io.ReadFull(reader, psk[:]); err != nil {
+		return nil, fmt.Errorf("curve25519: failed to read random bytes: %w", err)
+	}
+	return psk, nil
+}
+
+// String returns a placeholder rather than the key, as PrivateKey does.  Use
+// WireGuardString to get the key itself.
+func (psk PresharedKey) String() string {
+	return "curve25519.PresharedKey{REDACTED}"
+}
+
+// GoString is String for the %#v verb.
+func (psk PresharedKey) GoString() string {
+	return psk.String()
+}
+
+// WireGuardString returns psk as in WireGuard configurations: 44 characters
+// of padded standard base64.
+func (psk *PresharedKey) WireGuardString() string {
+	return wireGuardEncoding.EncodeToString(psk[:])
+}
+
+// ParseWireGuardPresharedKey parses a preshared key in the format of
+// WireGuardString.
+func ParseWireGuardPresharedKey(s string) (psk *PresharedKey, err error) {
+	psk = new(PresharedKey)
+	if err = decodeWireGuardKey(psk[:], s); err != nil {
+		return nil, err
+	}
+	return psk, nil
+}
+
+// WireGuardString returns pk as in WireGuard configurations and the output of
+// wg pubkey.
+func (pk *PublicKey) WireGuardString() string {
+	return wireGuardEncoding.EncodeToString(pk[:])
+}
+
+// ParseWireGuardPublicKey parses a public key in the format of
+// WireGuardString.
+func ParseWireGuardPublicKey(s string) (pk *PublicKey, err error) {
+	pk = new(PublicKey)
+	if err = decodeWireGuardKey(pk[:], s); err != nil {
+		return nil, err
+	}
+	return pk, nil
+}
+
+// WireGuardString returns sk as in WireGuard configurations and the output of
+// wg genkey.
+func (sk *PrivateKey) WireGuardString() string {
+	return wireGuardEncoding.EncodeToString(sk.raw[:])
+}
+
+// ParseWireGuardPrivateKey parses a private key in the format of
+// WireGuardString.  Like WireGuard, it clamps keys that aren't.
+func ParseWireGuardPrivateKey(s string) (sk *PrivateKey, err error) {
+	var raw [32]byte
+	if err = decodeWireGuardKey(raw[:], s); err != nil {
+		return nil, err
+	}
+	return NewPrivateKey(raw[:]), nil
+}
+
+var wireGuardEncoding = base64.StdEncoding.Strict()
+
+/* WireGuard only takes the 44 characters of padded base64 of a 32-byte key */
+func decodeWireGuardKey(dst []byte, s string) error {
+	if len(s) != wireGuardEncoding.EncodedLen(len(dst)) {
+		return ErrInvalidLength
+	}
+	decoded, err := wireGuardEncoding.DecodeString(s)
+	if err != nil {
+		return errors.New("curve25519: invalid WireGuard key encoding")
+	}
+	if len(decoded) != len(dst) {
+		return ErrInvalidLength
+	}
+	copy(dst, decoded)
+	return nil
+}
@@ -0,0 +1,164 @@
+package wireguard
+
+import (
+	"crypto/subtle"
+	"io"
+	"time"
+
+	curve25519 "github.com/moonfruit/go-curve25519"
+	"golang.org/x/crypto/blake2s"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// CookieRefreshTime is how long cookies, and the secrets they are made from,
+// are used.
+const CookieRefreshTime = 120 * time.Second
+
+const (
+	labelMAC1   = "mac1----"
+	labelCookie = "cookie--"
+	macSize     = 16
+)
+
+/* the keys and secret for the MACs of messages to an interface */
+type cookieChecker struct {
+	mac1Key    [32]byte
+	cookieKey  [32]byte
+	secret     [32]byte
+	secretTime time.Time
+}
+
+func (c *cookieChecker) init(publicKey *curve25519.PublicKey) {
+	c.mac1Key = labelHash(labelMAC1, publicKey)
+	c.cookieKey = labelHash(labelCookie, publicKey)
+}
+
+/* the keys and latest cookie for the MACs of messages to a peer */
+type cookieGenerator struct {
+	mac1Key     [32]byte
+	cookieKey   [32]byte
+	cookie      [macSize]byte
+	cookieTime  time.Time
+	lastMAC1    [macSize]byte
+	hasLastMAC1 bool
+}
+
+func (g *cookieGenerator) init(publicKey *curve25519.PublicKey) {
+	g.mac1Key = labelHash(labelMAC1, publicKey)
+	g.cookieKey = labelHash(labelCookie, publicKey)
+}
+
+/* appends mac1, and mac2 when there is a fresh cookie or zeros otherwise */
+func (g *cookieGenerator) addMACs(message []byte, now time.Time) []byte {
+	g.lastMAC1, g.hasLastMAC1 = mac(g.mac1Key[:], message), true
+	message = append(message, g.lastMAC1[:]...)
+	if g.cookieTime.IsZero() || now.Sub(g.cookieTime) >= CookieRefreshTime {
+		return append(message, make([]byte, macSize)...)
+	}
+	mac2 := mac(g.cookie[:], message)
+	return append(message, mac2[:]...)
+}
+
+// CheckMAC1 reports whether message is an initiation or response with a
+// valid mac1 for i.
+func (i *Interface) CheckMAC1(message []byte) bool {
+	n, ok := macOffset(message)
+	if !ok {
+		return false
+	}
+	expected := mac(i.cookies.mac1Key[:], message[:n])
+	return subtle.ConstantTimeCompare(expected[:], message[n:n+macSize]) == 1
+}
+
+// CheckMAC2 reports whether message is an initiation or response with a
+// valid mac2, made with the cookie of source: the IP address and UDP port the
+// message came from, in the same encoding as for CreateCookieReply.  An
+// interface under load should only process messages with a valid mac2, and
+// answer the others with a cookie reply.
+func (i *Interface) CheckMAC2(message, source []byte) bool {
+	n, ok := macOffset(message)
+	if !ok || i.cookies.secretTime.IsZero() || i.now().Sub(i.cookies.secretTime) >= CookieRefreshTime {
+		return false
+	}
+	cookie := mac(i.cookies.secret[:], source)
+	expected := mac(cookie[:], message[:n+macSize])
+	return subtle.ConstantTimeCompare(expected[:], message[n+macSize:n+2*macSize]) == 1
+}
+
+// CreateCookieReply returns the cookie reply to an initiation or response
+// from source, whose mac1 should have been checked.
+func (i *Interface) CreateCookieReply(message, source []byte) ([]byte, error) {
+	n, ok := macOffset(message)
+	if !ok {
+		return nil, ErrInvalidMessage
+	}
+	now := i.now()
+	if i.cookies.secretTime.IsZero() || now.Sub(i.cookies.secretTime) >= CookieRefreshTime {
+		if _, err := io.ReadFull(i.random(), i.cookies.secret[:]); err != nil {
+			return nil, err
+		}
+		i.cookies.secretTime = now
+	}
+	cookie := mac(i.cookies.secret[:], source)
+
+	reply := make([]byte, 8+chacha20poly1305.NonceSizeX, MessageCookieReplySize)
+	reply[0] = MessageCookieReplyType
+	copy(reply[4:8], message[4:8])
+	nonce := reply[8:]
+	if _, err := io.ReadFull(i.random(), nonce); err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.NewX(i.cookies.cookieKey[:])
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(reply, nonce, cookie[:], message[n:n+macSize]), nil
+}
+
+// ConsumeCookieReply takes the cookie of a reply from p, to the last
+// initiation or response sent to it, for the mac2 of the next ones.
+func (p *Peer) ConsumeCookieReply(message []byte) error {
+	if len(message) != MessageCookieReplySize || !checkType(message, MessageCookieReplyType) ||
+		!p.cookies.hasLastMAC1 {
+		return ErrInvalidMessage
+	}
+	aead, err := chacha20poly1305.NewX(p.cookies.cookieKey[:])
+	if err != nil {
+		return err
+	}
+	nonce := message[8 : 8+chacha20poly1305.NonceSizeX]
+	cookie, err := aead.Open(nil, nonce, message[8+len(nonce):], p.cookies.lastMAC1[:])
+	if err != nil {
+		return ErrDecrypt
+	}
+	copy(p.cookies.cookie[:], cookie)
+	p.cookies.cookieTime = p.iface.now()
+	return nil
+}
+
+/* the offset of mac1 in an initiation or response, which mac2 follows */
+func macOffset(message []byte) (int, bool) {
+	switch {
+	case len(message) == MessageInitiationSize && checkType(message, MessageInitiationType):
+		return MessageInitiationSize - 2*macSize, true
+	case len(message) == MessageResponseSize && checkType(message, MessageResponseType):
+		return MessageResponseSize - 2*macSize, true
+	}
+	return 0, false
+}
+
+/* Hash(label || public key) */
+func labelHash(label string, publicKey *curve25519.PublicKey) [32]byte {
+	return blake2s.Sum256(append([]byte(label), publicKey[:]...))
+}
+
+/* MAC(key, data), keyed BLAKE2s-128 */
+func mac(key, data []byte) (sum [macSize]byte) {
+	h, err := blake2s.New128(key)
+	if err != nil {
+		panic(err)
+	}
+	h.Write(data)
+	h.Sum(sum[:0])
+	return
+}
@@ -0,0 +1,40 @@
+package wireguard
+
+import (
+	"bytes"
+	"encoding/binary"
+	"time"
+)
+
+// TimestampSize is the size of a TAI64N timestamp.
+const TimestampSize = 12
+
+// Timestamp is a TAI64N timestamp, which initiations carry so that
+// responders can reject replays.
+type Timestamp [TimestampSize]byte
+
+const (
+	/* the TAI64 label of the Unix epoch: 2^62, plus the 10 seconds that TAI
+	 * was ahead of UTC then */
+	tai64Epoch = 0x400000000000000a
+	/* as WireGuard, keep only about 16ms of precision, so that timestamps
+	 * don't tell the exact time of the peer */
+	whitenerMask = 0x1000000 - 1
+)
+
+// NewTimestamp returns the timestamp of t, rounded down as WireGuard does.
+func NewTimestamp(t time.Time) (ts Timestamp) {
+	binary.BigEndian.PutUint64(ts[:8], uint64(tai64Epoch+t.Unix()))
+	binary.BigEndian.PutUint32(ts[8:], uint32(t.Nanosecond())&^whitenerMask)
+	return
+}
+
+// Time returns ts as a time.
+func (ts Timestamp) Time() time.Time {
+	return time.Unix(int64(binary.BigEndian.Uint64(ts[:8])-tai64Epoch), int64(binary.BigEndian.Uint32(ts[8:])))
+}
+
+// After reports whether ts is later than other.
+func (ts Timestamp) After(other Timestamp) bool {
+	return bytes.Compare(ts[:], other[:]) > 0
+}
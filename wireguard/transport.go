@@ -0,0 +1,107 @@
+package wireguard
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/moonfruit/go-curve25519/noise"
+)
+
+// RejectAfterMessages is the number of messages after which a keypair must
+// not be used anymore.
+const RejectAfterMessages = 1<<64 - 1<<13 - 1
+
+// ErrKeypairExhausted is returned when a keypair has sent
+// RejectAfterMessages messages.
+var ErrKeypairExhausted = errors.New("wireguard: keypair exhausted")
+
+// Keypair is the transport keys of a session.  It must not be used
+// concurrently.
+type Keypair struct {
+	// LocalIndex is the index of the session in the messages of the peer,
+	// and RemoteIndex its index in the messages to the peer.
+	LocalIndex  uint32
+	RemoteIndex uint32
+
+	send    *noise.CipherState
+	receive *noise.CipherState
+	replay  replayFilter
+}
+
+func newKeypair(localIndex, remoteIndex uint32, send, receive *noise.CipherState) *Keypair {
+	return &Keypair{LocalIndex: localIndex, RemoteIndex: remoteIndex, send: send, receive: receive}
+}
+
+// Encrypt appends the transport data message carrying packet to out.
+// WireGuard pads packets with zeros to a multiple of 16 bytes first, which
+// is left to the caller, and sends an empty packet as a keepalive.
+func (kp *Keypair) Encrypt(out, packet []byte) ([]byte, error) {
+	counter := kp.send.Nonce()
+	if counter >= RejectAfterMessages {
+		return nil, ErrKeypairExhausted
+	}
+	var header [MessageTransportHeaderSize]byte
+	header[0] = MessageTransportType
+	binary.LittleEndian.PutUint32(header[4:], kp.RemoteIndex)
+	binary.LittleEndian.PutUint64(header[8:], counter)
+	return kp.send.Encrypt(append(out, header[:]...), nil, packet)
+}
+
+// Decrypt checks a transport data message for kp and appends its packet to
+// out.  Messages may arrive out of order, but each is accepted only once.
+func (kp *Keypair) Decrypt(out, message []byte) ([]byte, error) {
+	if len(message) < MessageTransportHeaderSize+16 || !checkType(message, MessageTransportType) ||
+		binary.LittleEndian.Uint32(message[4:]) != kp.LocalIndex {
+		return nil, ErrInvalidMessage
+	}
+	counter := binary.LittleEndian.Uint64(message[8:])
+	if counter >= RejectAfterMessages || kp.replay.seen(counter) {
+		return nil, ErrReplay
+	}
+	kp.receive.SetNonce(counter)
+	packet, err := kp.receive.Decrypt(out, nil, message[MessageTransportHeaderSize:])
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	kp.replay.accept(counter)
+	return packet, nil
+}
+
+const (
+	replayBlocks = 128
+	/* counters this far behind the greatest one received are still accepted;
+	 * the block of the greatest one is only partly in the window */
+	replayWindow = (replayBlocks - 1) * 64
+)
+
+/* the sliding window of received counters, a ring of 64-bit blocks */
+type replayFilter struct {
+	greatest uint64
+	ring     [replayBlocks]uint64
+}
+
+/* whether counter was received already, or is too old to tell */
+func (f *replayFilter) seen(counter uint64) bool {
+	if counter > f.greatest {
+		return false
+	}
+	if f.greatest-counter > replayWindow {
+		return true
+	}
+	return f.ring[counter/64%replayBlocks]&(1<<(counter%64)) != 0
+}
+
+func (f *replayFilter) accept(counter uint64) {
+	if counter > f.greatest {
+		/* clear the blocks that the window moves into */
+		current, target := f.greatest/64, counter/64
+		if target-current > replayBlocks {
+			current = target - replayBlocks
+		}
+		for block := current + 1; block <= target; block++ {
+			f.ring[block%replayBlocks] = 0
+		}
+		f.greatest = counter
+	}
+	f.ring[counter/64%replayBlocks] |= 1 << (counter % 64)
+}
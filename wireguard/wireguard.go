@@ -0,0 +1,327 @@
+// Package wireguard implements the handshake of WireGuard, which is
+// Noise_IKpsk2 of the noise package, with the MACs and cookies that protect
+// it and the transport data messages that follow.  It builds and consumes
+// messages only: the caller sends and receives them, picks the indices that
+// identify sessions and runs the timers of the protocol.
+//
+// Both sides create an Interface with their private key and add the other as
+// a Peer.  The initiator sends the message of CreateInitiation; the responder
+// passes it to ConsumeInitiation and sends the message of CreateResponse,
+// which the initiator passes to ConsumeResponse.  Both then have a Keypair
+// for transport data messages, which the responder must not use to send
+// until it has received one.
+package wireguard
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+
+	curve25519 "github.com/moonfruit/go-curve25519"
+	"github.com/moonfruit/go-curve25519/noise"
+)
+
+// Identifier is the prologue of the handshake.
+const Identifier = "WireGuard v1 zx2c4 Jason@zx2c4.com"
+
+// Message types.
+const (
+	MessageInitiationType  = 1
+	MessageResponseType    = 2
+	MessageCookieReplyType = 3
+	MessageTransportType   = 4
+)
+
+// Message sizes.  Transport data messages are the header, the packet and a
+// 16-byte tag.
+const (
+	MessageInitiationSize      = 148
+	MessageResponseSize        = 92
+	MessageCookieReplySize     = 64
+	MessageTransportHeaderSize = 16
+)
+
+/* a responder takes at most one initiation per interval from each peer */
+const initiationInterval = time.Second / 50
+
+var (
+	// ErrInvalidMessage is returned for messages of the wrong type, size or
+	// receiver index.
+	ErrInvalidMessage = errors.New("wireguard: invalid message")
+	// ErrInvalidMAC is returned for handshake messages with an invalid mac1.
+	ErrInvalidMAC = errors.New("wireguard: invalid mac1")
+	// ErrDecrypt is returned when a message fails to authenticate.
+	ErrDecrypt = errors.New("wireguard: message authentication failed")
+	// ErrUnknownPeer is returned for initiations from a static key that
+	// isn't a peer of the interface.
+	ErrUnknownPeer = errors.New("wireguard: unknown peer")
+	// ErrReplay is returned for initiations whose timestamp isn't after the
+	// last one of the peer, and for transport data messages received before.
+	ErrReplay = errors.New("wireguard: replayed message")
+	// ErrFlood is returned for initiations that follow the last one of the
+	// peer too closely.
+	ErrFlood = errors.New("wireguard: too many initiations")
+)
+
+var (
+	handshakePattern = mustPSK(noise.HandshakeIK, 2)
+	cipherSuite      = noise.CipherSuite{Cipher: noise.ChaChaPoly, Hash: noise.BLAKE2s}
+)
+
+func mustPSK(pattern noise.HandshakePattern, position int) noise.HandshakePattern {
+	pattern, err := pattern.WithPSK(position)
+	if err != nil {
+		panic(err)
+	}
+	return pattern
+}
+
+// Interface is the local side of handshakes, with its static key and peers.
+// It must not be used concurrently.
+type Interface struct {
+	// Random is the source of ephemeral keys, cookie secrets and nonces,
+	// crypto/rand when nil.
+	Random io.Reader
+	// Now is the clock of timestamps and cookies, time.Now when nil.
+	Now func() time.Time
+
+	privateKey *curve25519.PrivateKey
+	publicKey  curve25519.PublicKey
+	peers      map[curve25519.PublicKey]*Peer
+	cookies    cookieChecker
+}
+
+// NewInterface returns an interface with privateKey and no peers.
+func NewInterface(privateKey *curve25519.PrivateKey) *Interface {
+	i := &Interface{
+		privateKey: privateKey,
+		publicKey:  *privateKey.Public(),
+		peers:      make(map[curve25519.PublicKey]*Peer),
+	}
+	i.cookies.init(&i.publicKey)
+	return i
+}
+
+// PublicKey returns the public key of i.
+func (i *Interface) PublicKey() *curve25519.PublicKey {
+	return &i.publicKey
+}
+
+// AddPeer adds the peer with publicKey, and presharedKey unless it is nil.
+func (i *Interface) AddPeer(publicKey *curve25519.PublicKey, presharedKey *curve25519.PresharedKey) (*Peer, error) {
+	if *publicKey == i.publicKey {
+		return nil, errors.New("wireguard: peer has the key of the interface")
+	}
+	if _, ok := i.peers[*publicKey]; ok {
+		return nil, errors.New("wireguard: peer already added")
+	}
+	peer := &Peer{iface: i, publicKey: *publicKey}
+	if presharedKey != nil {
+		peer.presharedKey = *presharedKey
+	}
+	peer.cookies.init(publicKey)
+	i.peers[*publicKey] = peer
+	return peer, nil
+}
+
+// Peer returns the peer with publicKey, or nil.
+func (i *Interface) Peer(publicKey *curve25519.PublicKey) *Peer {
+	return i.peers[*publicKey]
+}
+
+// RemovePeer removes the peer with publicKey, if any.
+func (i *Interface) RemovePeer(publicKey *curve25519.PublicKey) {
+	delete(i.peers, *publicKey)
+}
+
+func (i *Interface) random() io.Reader {
+	if i.Random == nil {
+		return rand.Reader
+	}
+	return i.Random
+}
+
+func (i *Interface) now() time.Time {
+	if i.Now == nil {
+		return time.Now()
+	}
+	return i.Now()
+}
+
+// Peer is a remote side of handshakes.
+type Peer struct {
+	iface        *Interface
+	publicKey    curve25519.PublicKey
+	presharedKey curve25519.PresharedKey
+	cookies      cookieGenerator
+
+	/* of the last initiation consumed */
+	lastTimestamp  Timestamp
+	lastInitiation time.Time
+}
+
+// PublicKey returns the public key of p.
+func (p *Peer) PublicKey() *curve25519.PublicKey {
+	return &p.publicKey
+}
+
+// Handshake is a handshake in progress with a peer.
+type Handshake struct {
+	peer        *Peer
+	state       *noise.HandshakeState
+	localIndex  uint32
+	remoteIndex uint32
+	timestamp   Timestamp
+}
+
+// Peer returns the peer of h.
+func (h *Handshake) Peer() *Peer {
+	return h.peer
+}
+
+// Timestamp returns the timestamp of the initiation.
+func (h *Handshake) Timestamp() Timestamp {
+	return h.timestamp
+}
+
+func (i *Interface) newHandshakeState(initiator bool, peer *Peer) (*noise.HandshakeState, error) {
+	config := noise.Config{
+		Pattern:     handshakePattern,
+		CipherSuite: cipherSuite,
+		Initiator:   initiator,
+		Prologue:    []byte(Identifier),
+		StaticKey:   i.privateKey,
+		Random:      i.random(),
+	}
+	if peer != nil {
+		config.PeerStatic = &peer.publicKey
+		config.PresharedKeys = [][]byte{peer.presharedKey[:]}
+	} else {
+		/* the responder sets the key of the peer once it knows the peer */
+		config.PresharedKeys = [][]byte{make([]byte, 32)}
+	}
+	return noise.NewHandshakeState(config)
+}
+
+// CreateInitiation starts a handshake with peer, which will identify it by
+// localIndex, and returns the initiation to send.
+func (i *Interface) CreateInitiation(peer *Peer, localIndex uint32) (h *Handshake, message []byte, err error) {
+	h = &Handshake{peer: peer, localIndex: localIndex, timestamp: NewTimestamp(i.now())}
+	if h.state, err = i.newHandshakeState(true, peer); err != nil {
+		return nil, nil, err
+	}
+	message = make([]byte, 8, MessageInitiationSize)
+	message[0] = MessageInitiationType
+	binary.LittleEndian.PutUint32(message[4:], localIndex)
+	if message, _, _, err = h.state.WriteMessage(message, h.timestamp[:]); err != nil {
+		return nil, nil, err
+	}
+	return h, peer.cookies.addMACs(message, i.now()), nil
+}
+
+// ConsumeInitiation checks and processes an initiation to i, and returns the
+// handshake to pass to CreateResponse.  It doesn't check mac2, which an
+// interface under load should do first with CheckMAC2.
+func (i *Interface) ConsumeInitiation(message []byte) (h *Handshake, err error) {
+	if len(message) != MessageInitiationSize || !checkType(message, MessageInitiationType) {
+		return nil, ErrInvalidMessage
+	}
+	if !i.CheckMAC1(message) {
+		return nil, ErrInvalidMAC
+	}
+	h = &Handshake{remoteIndex: binary.LittleEndian.Uint32(message[4:])}
+	if h.state, err = i.newHandshakeState(false, nil); err != nil {
+		return nil, err
+	}
+	payload, _, _, err := h.state.ReadMessage(nil, message[8:MessageInitiationSize-2*macSize])
+	if err != nil {
+		return nil, handshakeError(err)
+	}
+
+	if h.peer = i.peers[*h.state.PeerStatic()]; h.peer == nil {
+		return nil, ErrUnknownPeer
+	}
+	copy(h.timestamp[:], payload)
+	if !h.timestamp.After(h.peer.lastTimestamp) {
+		return nil, ErrReplay
+	}
+	now := i.now()
+	if !h.peer.lastInitiation.IsZero() && now.Sub(h.peer.lastInitiation) < initiationInterval {
+		return nil, ErrFlood
+	}
+	if err = h.state.SetPresharedKeys([][]byte{h.peer.presharedKey[:]}); err != nil {
+		return nil, err
+	}
+	h.peer.lastTimestamp, h.peer.lastInitiation = h.timestamp, now
+	return h, nil
+}
+
+// CreateResponse answers the initiation of h, which the peer will identify
+// by localIndex, and returns the keypair of the session and the response to
+// send.
+func (h *Handshake) CreateResponse(localIndex uint32) (keypair *Keypair, message []byte, err error) {
+	h.localIndex = localIndex
+	message = make([]byte, 12, MessageResponseSize)
+	message[0] = MessageResponseType
+	binary.LittleEndian.PutUint32(message[4:], localIndex)
+	binary.LittleEndian.PutUint32(message[8:], h.remoteIndex)
+	message, c1, c2, err := h.state.WriteMessage(message, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	message = h.peer.cookies.addMACs(message, h.peer.iface.now())
+	return newKeypair(h.localIndex, h.remoteIndex, c2, c1), message, nil
+}
+
+// ConsumeResponse checks and processes the response to the initiation of h,
+// and returns the keypair of the session.  As ConsumeInitiation, it doesn't
+// check mac2.
+func (h *Handshake) ConsumeResponse(message []byte) (keypair *Keypair, err error) {
+	if len(message) != MessageResponseSize || !checkType(message, MessageResponseType) ||
+		binary.LittleEndian.Uint32(message[8:]) != h.localIndex {
+		return nil, ErrInvalidMessage
+	}
+	if !h.peer.iface.CheckMAC1(message) {
+		return nil, ErrInvalidMAC
+	}
+	_, c1, c2, err := h.state.ReadMessage(nil, message[12:MessageResponseSize-2*macSize])
+	if err != nil {
+		return nil, handshakeError(err)
+	}
+	h.remoteIndex = binary.LittleEndian.Uint32(message[4:])
+	return newKeypair(h.localIndex, h.remoteIndex, c1, c2), nil
+}
+
+// ReceiverIndex returns the index that a response, cookie reply or transport
+// data message is for, so that the caller can find its handshake, peer or
+// keypair.
+func ReceiverIndex(message []byte) (index uint32, ok bool) {
+	if len(message) < 8 {
+		return 0, false
+	}
+	switch {
+	case checkType(message, MessageResponseType):
+		if len(message) < 12 {
+			return 0, false
+		}
+		return binary.LittleEndian.Uint32(message[8:]), true
+	case checkType(message, MessageCookieReplyType), checkType(message, MessageTransportType):
+		return binary.LittleEndian.Uint32(message[4:]), true
+	}
+	return 0, false
+}
+
+/* a message type is a little-endian 32-bit integer, of which the three
+ * reserved bytes must be zero */
+func checkType(message []byte, messageType byte) bool {
+	return message[0] == messageType && message[1] == 0 && message[2] == 0 && message[3] == 0
+}
+
+func handshakeError(err error) error {
+	if err == noise.ErrDecrypt {
+		return ErrDecrypt
+	}
+	return err
+}
@@ -0,0 +1,364 @@
+package wireguard
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	curve25519 "github.com/moonfruit/go-curve25519"
+	"github.com/stretchr/testify/require"
+)
+
+var reader = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+/* a clock that only moves when told to */
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func (c *clock) advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newInterface(c *clock) *Interface {
+	i := NewInterface(curve25519.GenerateKeyFrom(reader))
+	i.Random = reader
+	i.Now = c.Now
+	return i
+}
+
+func newPeers(t *testing.T, c *clock, psk *curve25519.PresharedKey) (initiator, responder *Interface) {
+	initiator, responder = newInterface(c), newInterface(c)
+	_, err := initiator.AddPeer(responder.PublicKey(), psk)
+	require.NoError(t, err)
+	_, err = responder.AddPeer(initiator.PublicKey(), psk)
+	require.NoError(t, err)
+	return
+}
+
+func handshake(t *testing.T, initiator, responder *Interface) (initiatorKeypair, responderKeypair *Keypair) {
+	h, initiation, err := initiator.CreateInitiation(initiator.Peer(responder.PublicKey()), 1)
+	require.NoError(t, err)
+	require.Len(t, initiation, MessageInitiationSize)
+	r, err := responder.ConsumeInitiation(initiation)
+	require.NoError(t, err)
+	require.Equal(t, initiator.PublicKey(), r.Peer().PublicKey())
+	require.Equal(t, h.Timestamp(), r.Timestamp())
+
+	responderKeypair, response, err := r.CreateResponse(2)
+	require.NoError(t, err)
+	require.Len(t, response, MessageResponseSize)
+	index, ok := ReceiverIndex(response)
+	require.True(t, ok)
+	require.Equal(t, uint32(1), index)
+	initiatorKeypair, err = h.ConsumeResponse(response)
+	require.NoError(t, err)
+	return
+}
+
+func readVector(t *testing.T) map[string]string {
+	file, err := os.Open(filepath.Join("testdata", "wireguard-go.txt"))
+	require.NoError(t, err)
+	defer file.Close()
+
+	vector := make(map[string]string)
+	for {
+		var name, value string
+		n, err := fmt.Fscanln(file, &name, &value)
+		if n == 0 && err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		vector[name] = value
+	}
+	return vector
+}
+
+func decodeHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
+
+func TestWireGuardGo(t *testing.T) {
+	// a session with wireguard-go as the responder, where this package sent a
+	// ping and wireguard-go the reply
+	vector := readVector(t)
+	initiatorKey := curve25519.NewPrivateKey(decodeHex(t, vector["initiator_private"]))
+	responderKey := curve25519.NewPrivateKey(decodeHex(t, vector["responder_private"]))
+	var psk curve25519.PresharedKey
+	copy(psk[:], decodeHex(t, vector["psk"]))
+	nanoseconds, err := strconv.ParseInt(vector["now"], 10, 64)
+	require.NoError(t, err)
+	now := time.Unix(0, nanoseconds)
+
+	initiator := NewInterface(initiatorKey)
+	initiator.Random = bytes.NewReader(decodeHex(t, vector["ephemeral"]))
+	initiator.Now = func() time.Time { return now }
+	peer, err := initiator.AddPeer(responderKey.Public(), &psk)
+	require.NoError(t, err)
+	h, initiation, err := initiator.CreateInitiation(peer, 0x11223344)
+	require.NoError(t, err)
+	require.Equal(t, vector["initiation"], hex.EncodeToString(initiation))
+
+	keypair, err := h.ConsumeResponse(decodeHex(t, vector["response"]))
+	require.NoError(t, err)
+	data, err := keypair.Encrypt(nil, decodeHex(t, vector["ping"]))
+	require.NoError(t, err)
+	require.Equal(t, vector["data"], hex.EncodeToString(data))
+	pong, err := keypair.Decrypt(nil, decodeHex(t, vector["transport"]))
+	require.NoError(t, err)
+	require.Equal(t, vector["pong"], hex.EncodeToString(pong))
+
+	// and the other way round, with this package as the responder
+	responder := NewInterface(responderKey)
+	_, err = responder.AddPeer(initiatorKey.Public(), &psk)
+	require.NoError(t, err)
+	r, err := responder.ConsumeInitiation(initiation)
+	require.NoError(t, err)
+	require.Equal(t, initiatorKey.Public(), r.Peer().PublicKey())
+	require.Equal(t, now.Truncate(time.Second), r.Timestamp().Time().Truncate(time.Second))
+}
+
+func TestHandshake(t *testing.T) {
+	c := &clock{time.Now()}
+	psk, err := curve25519.GeneratePresharedKey(reader)
+	require.NoError(t, err)
+	for _, psk := range []*curve25519.PresharedKey{nil, psk} {
+		initiator, responder := newPeers(t, c, psk)
+		initiatorKeypair, responderKeypair := handshake(t, initiator, responder)
+		require.Equal(t, initiatorKeypair.LocalIndex, responderKeypair.RemoteIndex)
+		require.Equal(t, initiatorKeypair.RemoteIndex, responderKeypair.LocalIndex)
+
+		for i, packet := range []string{"", "ping", "pong"} {
+			from, to := initiatorKeypair, responderKeypair
+			if i%2 == 0 {
+				from, to = to, from
+			}
+			message, err := from.Encrypt(nil, []byte(packet))
+			require.NoError(t, err)
+			require.Len(t, message, MessageTransportHeaderSize+len(packet)+16)
+			index, ok := ReceiverIndex(message)
+			require.True(t, ok)
+			require.Equal(t, to.LocalIndex, index)
+			decrypted, err := to.Decrypt(nil, message)
+			require.NoError(t, err)
+			require.Equal(t, packet, string(decrypted))
+		}
+		c.advance(time.Second)
+	}
+
+	// a psk on one side only
+	initiator, responder := newPeers(t, c, nil)
+	psk, err = curve25519.GeneratePresharedKey(reader)
+	require.NoError(t, err)
+	peer := initiator.Peer(responder.PublicKey())
+	initiator.RemovePeer(responder.PublicKey())
+	require.Nil(t, initiator.Peer(responder.PublicKey()))
+	peer, err = initiator.AddPeer(peer.PublicKey(), psk)
+	require.NoError(t, err)
+	h, initiation, err := initiator.CreateInitiation(peer, 1)
+	require.NoError(t, err)
+	r, err := responder.ConsumeInitiation(initiation)
+	require.NoError(t, err)
+	_, response, err := r.CreateResponse(2)
+	require.NoError(t, err)
+	_, err = h.ConsumeResponse(response)
+	require.Equal(t, ErrDecrypt, err)
+
+	_, err = initiator.AddPeer(responder.PublicKey(), nil)
+	require.Error(t, err)
+	_, err = initiator.AddPeer(initiator.PublicKey(), nil)
+	require.Error(t, err)
+}
+
+func TestInitiationChecks(t *testing.T) {
+	// at the start of the ~16ms steps of timestamps
+	c := &clock{time.Unix(1700000000, 0)}
+	initiator, responder := newPeers(t, c, nil)
+	peer := initiator.Peer(responder.PublicKey())
+
+	_, initiation, err := initiator.CreateInitiation(peer, 1)
+	require.NoError(t, err)
+	for _, i := range []int{0, 1, 4, 8, 40, 100, 116, 131} {
+		tampered := append([]byte{}, initiation...)
+		tampered[i] ^= 1
+		_, err = responder.ConsumeInitiation(tampered)
+		require.Error(t, err, i)
+	}
+	_, err = responder.ConsumeInitiation(initiation[:MessageInitiationSize-1])
+	require.Equal(t, ErrInvalidMessage, err)
+	// mac1 protects the whole message, and the AEADs what it doesn't
+	tampered := append([]byte{}, initiation...)
+	tampered[40] ^= 1
+	_, err = responder.ConsumeInitiation(tampered)
+	require.Equal(t, ErrInvalidMAC, err)
+	// mac2 isn't checked
+	tampered = append([]byte{}, initiation...)
+	tampered[MessageInitiationSize-1] ^= 1
+	_, err = responder.ConsumeInitiation(tampered)
+	require.NoError(t, err)
+
+	// replays, and initiations too close to the last one
+	_, err = responder.ConsumeInitiation(initiation)
+	require.Equal(t, ErrReplay, err)
+	c.advance(time.Millisecond)
+	_, initiation, err = initiator.CreateInitiation(peer, 1)
+	require.NoError(t, err)
+	_, err = responder.ConsumeInitiation(initiation)
+	require.Equal(t, ErrReplay, err)
+	c.advance(16 * time.Millisecond)
+	_, initiation, err = initiator.CreateInitiation(peer, 1)
+	require.NoError(t, err)
+	_, err = responder.ConsumeInitiation(initiation)
+	require.Equal(t, ErrFlood, err)
+	c.advance(initiationInterval - 17*time.Millisecond)
+	_, err = responder.ConsumeInitiation(initiation)
+	require.NoError(t, err)
+
+	// an unknown peer
+	stranger := newInterface(c)
+	_, err = stranger.AddPeer(responder.PublicKey(), nil)
+	require.NoError(t, err)
+	_, initiation, err = stranger.CreateInitiation(stranger.Peer(responder.PublicKey()), 1)
+	require.NoError(t, err)
+	_, err = responder.ConsumeInitiation(initiation)
+	require.Equal(t, ErrUnknownPeer, err)
+
+	// a response to another initiation
+	c.advance(time.Second)
+	h, initiation, err := initiator.CreateInitiation(peer, 1)
+	require.NoError(t, err)
+	r, err := responder.ConsumeInitiation(initiation)
+	require.NoError(t, err)
+	other, _, err := initiator.CreateInitiation(peer, 3)
+	require.NoError(t, err)
+	_, response, err := r.CreateResponse(2)
+	require.NoError(t, err)
+	_, err = other.ConsumeResponse(response)
+	require.Equal(t, ErrInvalidMessage, err)
+	_, err = h.ConsumeResponse(response)
+	require.NoError(t, err)
+	_, err = h.ConsumeResponse(response)
+	require.Error(t, err)
+}
+
+func TestCookies(t *testing.T) {
+	c := &clock{time.Now()}
+	initiator, responder := newPeers(t, c, nil)
+	peer := initiator.Peer(responder.PublicKey())
+	source := []byte{127, 0, 0, 1, 0xca, 0x6c}
+
+	// the responder is under load, and only takes initiations with a cookie
+	_, initiation, err := initiator.CreateInitiation(peer, 7)
+	require.NoError(t, err)
+	require.True(t, responder.CheckMAC1(initiation))
+	require.False(t, responder.CheckMAC2(initiation, source))
+	reply, err := responder.CreateCookieReply(initiation, source)
+	require.NoError(t, err)
+	require.Len(t, reply, MessageCookieReplySize)
+	index, ok := ReceiverIndex(reply)
+	require.True(t, ok)
+	require.Equal(t, uint32(7), index)
+
+	tampered := append([]byte{}, reply...)
+	tampered[40] ^= 1
+	require.Equal(t, ErrDecrypt, peer.ConsumeCookieReply(tampered))
+	require.NoError(t, peer.ConsumeCookieReply(reply))
+	// the reply is bound to the message it answered
+	c.advance(time.Second)
+	_, initiation, err = initiator.CreateInitiation(peer, 8)
+	require.NoError(t, err)
+	require.Equal(t, ErrDecrypt, peer.ConsumeCookieReply(reply))
+	require.True(t, responder.CheckMAC2(initiation, source))
+	require.False(t, responder.CheckMAC2(initiation, []byte{127, 0, 0, 2, 0xca, 0x6c}))
+	_, err = responder.ConsumeInitiation(initiation)
+	require.NoError(t, err)
+
+	// cookies expire, as do the secrets they are made from
+	c.advance(CookieRefreshTime)
+	_, initiation, err = initiator.CreateInitiation(peer, 9)
+	require.NoError(t, err)
+	require.Equal(t, make([]byte, macSize), initiation[MessageInitiationSize-macSize:])
+	require.False(t, responder.CheckMAC2(initiation, source))
+
+	// the initiator may be under load too
+	_, err = initiator.CreateCookieReply([]byte("not a handshake message"), source)
+	require.Equal(t, ErrInvalidMessage, err)
+	r, err := responder.ConsumeInitiation(initiation)
+	require.NoError(t, err)
+	_, response, err := r.CreateResponse(10)
+	require.NoError(t, err)
+	reply, err = initiator.CreateCookieReply(response, source)
+	require.NoError(t, err)
+	require.NoError(t, responder.Peer(initiator.PublicKey()).ConsumeCookieReply(reply))
+}
+
+func TestTransport(t *testing.T) {
+	c := &clock{time.Now()}
+	initiator, responder := newPeers(t, c, nil)
+	send, receive := handshake(t, initiator, responder)
+
+	var messages [][]byte
+	for i := 0; i < 3*replayWindow; i++ {
+		message, err := send.Encrypt(nil, []byte{byte(i)})
+		require.NoError(t, err)
+		messages = append(messages, message)
+	}
+
+	_, err := receive.Decrypt(nil, messages[0][:MessageTransportHeaderSize+15])
+	require.Equal(t, ErrInvalidMessage, err)
+	tampered := append([]byte{}, messages[0]...)
+	tampered[4] ^= 1
+	_, err = receive.Decrypt(nil, tampered)
+	require.Equal(t, ErrInvalidMessage, err)
+	tampered = append(tampered[:0], messages[0]...)
+	tampered[len(tampered)-1] ^= 1
+	_, err = receive.Decrypt(nil, tampered)
+	require.Equal(t, ErrDecrypt, err)
+
+	// out of order within the window, but once only
+	for _, i := range []int{5, 0, 3, 100, 1, replayWindow + 100, replayWindow + 90, 101} {
+		packet, err := receive.Decrypt(nil, messages[i])
+		require.NoError(t, err, i)
+		require.Equal(t, []byte{byte(i)}, packet)
+		_, err = receive.Decrypt(nil, messages[i])
+		require.Equal(t, ErrReplay, err, i)
+	}
+	_, err = receive.Decrypt(nil, messages[99])
+	require.Equal(t, ErrReplay, err)
+	_, err = receive.Decrypt(nil, messages[200])
+	require.NoError(t, err)
+	// a jump over the whole window
+	_, err = receive.Decrypt(nil, messages[3*replayWindow-1])
+	require.NoError(t, err)
+	_, err = receive.Decrypt(nil, messages[2*replayWindow])
+	require.NoError(t, err)
+	_, err = receive.Decrypt(nil, messages[2*replayWindow-2])
+	require.Equal(t, ErrReplay, err)
+
+	send.send.SetNonce(RejectAfterMessages)
+	_, err = send.Encrypt(nil, nil)
+	require.Equal(t, ErrKeypairExhausted, err)
+}
+
+func TestTimestamp(t *testing.T) {
+	now := time.Unix(1700000000, 999999999)
+	timestamp := NewTimestamp(now)
+	require.Equal(t, "400000006553f10a3b000000", hex.EncodeToString(timestamp[:]))
+	require.True(t, now.Sub(timestamp.Time()) < 20*time.Millisecond)
+	require.False(t, timestamp.After(timestamp))
+	require.True(t, NewTimestamp(now.Add(20*time.Millisecond)).After(timestamp))
+	require.False(t, NewTimestamp(now.Add(-time.Second)).After(timestamp))
+}
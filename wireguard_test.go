@@ -0,0 +1,55 @@
+package curve25519
+
+import (
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWireGuardKeys(t *testing.T) {
+	// the keys of the example in wg(8)
+	privateKey, err := ParseWireGuardPrivateKey("yAnz5TF+lXXJte14tji3zlMNq+hd2rYUIgJBgB3fBmk=")
+	require.NoError(t, err)
+	require.Equal(t, "yAnz5TF+lXXJte14tji3zlMNq+hd2rYUIgJBgB3fBmk=", privateKey.WireGuardString())
+	require.Equal(t, "HIgo9xNzJMWLKASShiTqIybxZ0U3wGLiUeJ1PKf8ykw=", privateKey.Public().WireGuardString())
+
+	publicKey, err := ParseWireGuardPublicKey("HIgo9xNzJMWLKASShiTqIybxZ0U3wGLiUeJ1PKf8ykw=")
+	require.NoError(t, err)
+	require.Equal(t, privateKey.Public(), publicKey)
+
+	// unclamped private keys are clamped
+	unclamped := make([]byte, 32)
+	reader.Read(unclamped)
+	unclamped[0] |= 7
+	privateKey, err = ParseWireGuardPrivateKey(base64.StdEncoding.EncodeToString(unclamped))
+	require.NoError(t, err)
+	require.Equal(t, NewPrivateKey(unclamped), privateKey)
+
+	psk, err := GeneratePresharedKey(reader)
+	require.NoError(t, err)
+	require.Len(t, psk.WireGuardString(), 44)
+	decoded, err := ParseWireGuardPresharedKey(psk.WireGuardString())
+	require.NoError(t, err)
+	require.Equal(t, psk, decoded)
+	require.NotContains(t, fmt.Sprint(*psk), psk.WireGuardString())
+	require.NotContains(t, fmt.Sprintf("%#v", *psk), psk.WireGuardString())
+
+	for _, s := range []string{
+		"",
+		"HIgo9xNzJMWLKASShiTqIybxZ0U3wGLiUeJ1PKf8ykw",
+		"HIgo9xNzJMWLKASShiTqIybxZ0U3wGLiUeJ1PKf8ykw==",
+		"HIgo9xNzJMWLKASShiTqIybxZ0U3wGLiUeJ1PKf8yk==",
+		"HIgo9xNzJMWLKASShiTqIybxZ0U3wGLiUeJ1PKf8ykx=",
+		"HIgo9xNzJMWLKASShiTqIybxZ0U3wGLiUeJ1PKf8yk-=",
+		"1e5fa3f9e3cdcdc2d9e2a7fd2c7b94a8b1fb1e8a4e1e5fa3f9e3cdcdc2d9e2a7",
+	} {
+		_, err = ParseWireGuardPublicKey(s)
+		require.Error(t, err, s)
+		_, err = ParseWireGuardPrivateKey(s)
+		require.Error(t, err, s)
+		_, err = ParseWireGuardPresharedKey(s)
+		require.Error(t, err, s)
+	}
+}
@@ -0,0 +1,74 @@
+package curve25519
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type wycheproofX25519 struct {
+	TestGroups []struct {
+		Tests []struct {
+			TcID    int      `json:"tcId"`
+			Comment string   `json:"comment"`
+			Flags   []string `json:"flags"`
+			Public  string   `json:"public"`
+			Private string   `json:"private"`
+			Shared  string   `json:"shared"`
+			Result  string   `json:"result"`
+		} `json:"tests"`
+	} `json:"testGroups"`
+}
+
+func TestSharedSecretChecked(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "x25519_test.json"))
+	require.NoError(t, err)
+	var vectors wycheproofX25519
+	require.NoError(t, json.Unmarshal(data, &vectors))
+
+	checked := map[string]func(sk *PrivateKey, pk *PublicKey) ([]byte, error){
+		"x/crypto": (*PrivateKey).SharedSecretChecked,
+		"curve":    (*PrivateKey).mySharedSecretChecked,
+	}
+
+	tested := 0
+	for _, group := range vectors.TestGroups {
+		for _, test := range group.Tests {
+			lowOrder := false
+			for _, flag := range test.Flags {
+				if flag == "LowOrderPublic" || flag == "ZeroSharedSecret" {
+					lowOrder = true
+				}
+			}
+
+			privateKey := NewPrivateKey(decodeHex(t, test.Private))
+			publicKey := NewPublicKey(decodeHex(t, test.Public))
+			for name, sharedSecret := range checked {
+				ss, err := sharedSecret(privateKey, publicKey)
+				if lowOrder {
+					require.Equal(t, ErrLowOrderPoint, err, "%s #%d: %s", name, test.TcID, test.Comment)
+				} else {
+					require.NoError(t, err, "%s #%d: %s", name, test.TcID, test.Comment)
+					require.Equal(t, decodeHex(t, test.Shared), ss, "%s #%d: %s", name, test.TcID, test.Comment)
+				}
+			}
+			tested++
+		}
+	}
+	require.Equal(t, 518, tested)
+
+	for _, point := range lowOrderPoints {
+		privateKey := GenerateKeyFrom(reader)
+		for _, top := range []byte{0, 0x80} {
+			publicKey := NewPublicKey(point)
+			publicKey[31] |= top
+			for _, sharedSecret := range checked {
+				_, err := sharedSecret(privateKey, publicKey)
+				require.Equal(t, ErrLowOrderPoint, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+#!/usr/bin/env python3
+"""Checks the shared keys of x3dh.txt.
+
+A plain implementation of X3DH as given in "The X3DH Key Agreement Protocol"
+(Signal, revision 1, 2016-11-04), sections 2.2 and 3.3, with X25519 from RFC
+7748 and HKDF-SHA256 from RFC 5869, using only the Python standard library
+and sharing no code with the Go package.  It recomputes the shared key of
+each line from the private keys, and fails on any that differs.  The
+signatures are those of the package's own Sign, which it doesn't check.
+
+    python3 testdata/x3dh.py < testdata/x3dh.txt
+"""
+
+import hashlib
+import hmac
+import sys
+
+p = 2**255 - 19
+
+
+# RFC 7748, section 5
+def x25519(k, u):
+    k = bytearray(k)
+    k[0] &= 248
+    k[31] &= 127
+    k[31] |= 64
+    k = int.from_bytes(k, 'little')
+    u = int.from_bytes(u, 'little') & (2**255 - 1)
+    x1, x2, z2, x3, z3 = u, 1, 0, u, 1
+    swap = 0
+    for t in reversed(range(255)):
+        bit = k >> t & 1
+        swap ^= bit
+        if swap:
+            x2, x3, z2, z3 = x3, x2, z3, z2
+        swap = bit
+        A, B = x2 + z2, x2 - z2
+        AA, BB = A * A, B * B
+        E = AA - BB
+        C, D = x3 + z3, x3 - z3
+        DA, CB = D * A, C * B
+        x3, z3 = (DA + CB)**2 % p, x1 * (DA - CB)**2 % p
+        x2, z2 = AA * BB % p, E * (AA + 121665 * E) % p
+    if swap:
+        x2, z2 = x3, z3
+    return (x2 * pow(z2, p - 2, p) % p).to_bytes(32, 'little')
+
+
+def public(k):
+    return x25519(k, (9).to_bytes(32, 'little'))
+
+
+# RFC 5869, with a zero salt of the hash length
+def hkdf(ikm, info, length):
+    prk = hmac.new(bytes(32), ikm, hashlib.sha256).digest()
+    okm, block = b'', b''
+    for i in range(1, -(-length // 32) + 1):
+        block = hmac.new(prk, block + info + bytes([i]), hashlib.sha256).digest()
+        okm += block
+    return okm[:length]
+
+
+# section 3.3, as the initiator
+def shared_key(ika, ikb, spkb, opkb, eka, info):
+    dh1 = x25519(ika, public(spkb))
+    dh2 = x25519(eka, public(ikb))
+    dh3 = x25519(eka, public(spkb))
+    km = dh1 + dh2 + dh3
+    if opkb is not None:
+        km += x25519(eka, public(opkb))
+    return hkdf(b'\xff' * 32 + km, info, 32)
+
+
+def main():
+    # RFC 7748, section 6.1
+    alice = bytes.fromhex('77076d0a7318a57d3c16c17251b26645df4c2f87ebc0992ab177fba51db92c2a')
+    assert public(alice).hex() == '8520f0098930a754748b7ddcb43ef75a0dbf3a0d26381af4eba4a98eaa9b4e6a'
+
+    failed = 0
+    for n, line in enumerate(sys.stdin, 1):
+        ika, ikb, spkb, opkb, eka, _, expected = line.split()
+        key = shared_key(bytes.fromhex(ika), bytes.fromhex(ikb), bytes.fromhex(spkb),
+                         None if opkb == '-' else bytes.fromhex(opkb),
+                         bytes.fromhex(eka), b'x3dh test')
+        if key.hex() != expected:
+            print('line %d: shared key %s, want %s' % (n, key.hex(), expected))
+            failed += 1
+    sys.exit(1 if failed else 0)
+
+
+if __name__ == '__main__':
+    main()
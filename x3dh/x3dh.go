@@ -0,0 +1,200 @@
+// Package x3dh implements the X3DH key agreement protocol of Signal, with
+// X25519 keys and the EC-KCDSA signatures of this module.
+//
+// A responder (Bob) publishes a Bundle with its identity key, a signed
+// prekey and optionally a one-time prekey.  An initiator (Alice) calls
+// Initiate with the bundle, which gives it a shared key, the associated data
+// and an InitialMessage for Bob, who calls Respond with the message and his
+// private keys to get the same shared key and associated data.
+package x3dh
+
+import (
+	"crypto/sha256"
+	"errors"
+	"io"
+
+	curve25519 "github.com/moonfruit/go-curve25519"
+	"golang.org/x/crypto/hkdf"
+)
+
+// SharedKeySize is the size of the shared key.
+const SharedKeySize = 32
+
+// ErrInvalidSignature is returned when the signature of a signed prekey
+// doesn't verify under the identity key.
+var ErrInvalidSignature = errors.New("x3dh: invalid signed prekey signature")
+
+// SignedPreKey is a medium-term prekey signed by an identity key.
+type SignedPreKey struct {
+	ID        uint32
+	Key       *curve25519.PrivateKey
+	Signature curve25519.Signature
+}
+
+// NewSignedPreKey signs key with identity, as Sign of the identity key does
+// over the 32-byte public key.
+func NewSignedPreKey(identity *curve25519.PrivateKey, id uint32, key *curve25519.PrivateKey) *SignedPreKey {
+	return &SignedPreKey{
+		ID:        id,
+		Key:       key,
+		Signature: *identity.Sign(key.Public()[:]),
+	}
+}
+
+// OneTimePreKey is a prekey used for a single X3DH run.
+type OneTimePreKey struct {
+	ID  uint32
+	Key *curve25519.PrivateKey
+}
+
+// Bundle is the public keys a responder publishes.
+type Bundle struct {
+	IdentityKey           curve25519.PublicKey
+	SignedPreKeyID        uint32
+	SignedPreKey          curve25519.PublicKey
+	SignedPreKeySignature curve25519.Signature
+	// OneTimePreKey is nil when the responder has run out of one-time
+	// prekeys.
+	OneTimePreKeyID uint32
+	OneTimePreKey   *curve25519.PublicKey
+}
+
+// NewBundle returns the bundle of identity, signedPreKey and oneTimePreKey,
+// which may be nil.
+func NewBundle(identity *curve25519.PrivateKey, signedPreKey *SignedPreKey, oneTimePreKey *OneTimePreKey) *Bundle {
+	bundle := &Bundle{
+		IdentityKey:           *identity.Public(),
+		SignedPreKeyID:        signedPreKey.ID,
+		SignedPreKey:          *signedPreKey.Key.Public(),
+		SignedPreKeySignature: signedPreKey.Signature,
+	}
+	if oneTimePreKey != nil {
+		bundle.OneTimePreKeyID = oneTimePreKey.ID
+		bundle.OneTimePreKey = oneTimePreKey.Key.Public()
+	}
+	return bundle
+}
+
+// Verify checks the signature of the signed prekey.
+func (b *Bundle) Verify() error {
+	if !curve25519.Verify(b.SignedPreKey[:], &b.SignedPreKeySignature, &b.IdentityKey, true) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// InitialMessage is what the initiator sends the responder, along with its
+// first ciphertext, so that the responder can compute the shared key.
+type InitialMessage struct {
+	IdentityKey     curve25519.PublicKey
+	EphemeralKey    curve25519.PublicKey
+	SignedPreKeyID  uint32
+	OneTimePreKeyID uint32
+	// HasOneTimePreKey tells whether OneTimePreKeyID was used.
+	HasOneTimePreKey bool
+}
+
+// Initiate verifies bundle and runs X3DH with it, with an ephemeral key
+// generated from reader.  The info string identifies the application.
+//
+// The associated data is the initiator's identity key followed by the
+// responder's, which the application should authenticate along with the
+// first message, e.g. as the AD of its AEAD.
+func Initiate(reader io.Reader, identity *curve25519.PrivateKey, bundle *Bundle, info []byte) (sharedKey, associatedData []byte, message *InitialMessage, err error) {
+	if err = bundle.Verify(); err != nil {
+		return nil, nil, nil, err
+	}
+	ephemeral, err := curve25519.GenerateKeyWithReader(reader)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	/* DH1 = DH(IKA, SPKB), DH2 = DH(EKA, IKB), DH3 = DH(EKA, SPKB) and
+	 * DH4 = DH(EKA, OPKB) */
+	exchanges := []exchange{
+		{identity, &bundle.SignedPreKey},
+		{ephemeral, &bundle.IdentityKey},
+		{ephemeral, &bundle.SignedPreKey},
+	}
+	message = &InitialMessage{
+		IdentityKey:    *identity.Public(),
+		EphemeralKey:   *ephemeral.Public(),
+		SignedPreKeyID: bundle.SignedPreKeyID,
+	}
+	if bundle.OneTimePreKey != nil {
+		exchanges = append(exchanges, exchange{ephemeral, bundle.OneTimePreKey})
+		message.OneTimePreKeyID = bundle.OneTimePreKeyID
+		message.HasOneTimePreKey = true
+	}
+
+	if sharedKey, err = agree(exchanges, info); err != nil {
+		return nil, nil, nil, err
+	}
+	return sharedKey, concatIdentities(&message.IdentityKey, &bundle.IdentityKey), message, nil
+}
+
+// Respond runs X3DH for message, with the responder's identity key and the
+// prekeys it names.  oneTimePreKey must be nil if and only if the message
+// used none; the caller should then delete it so that it is never reused.
+func Respond(identity *curve25519.PrivateKey, signedPreKey *SignedPreKey, oneTimePreKey *OneTimePreKey, message *InitialMessage, info []byte) (sharedKey, associatedData []byte, err error) {
+	if message.SignedPreKeyID != signedPreKey.ID {
+		return nil, nil, errors.New("x3dh: message is for another signed prekey")
+	}
+	if message.HasOneTimePreKey != (oneTimePreKey != nil) {
+		return nil, nil, errors.New("x3dh: one-time prekey mismatch")
+	}
+	if oneTimePreKey != nil && message.OneTimePreKeyID != oneTimePreKey.ID {
+		return nil, nil, errors.New("x3dh: message is for another one-time prekey")
+	}
+
+	exchanges := []exchange{
+		{signedPreKey.Key, &message.IdentityKey},
+		{identity, &message.EphemeralKey},
+		{signedPreKey.Key, &message.EphemeralKey},
+	}
+	if oneTimePreKey != nil {
+		exchanges = append(exchanges, exchange{oneTimePreKey.Key, &message.EphemeralKey})
+	}
+
+	if sharedKey, err = agree(exchanges, info); err != nil {
+		return nil, nil, err
+	}
+	return sharedKey, concatIdentities(&message.IdentityKey, identity.Public()), nil
+}
+
+type exchange struct {
+	sk *curve25519.PrivateKey
+	pk *curve25519.PublicKey
+}
+
+/* the shared key from the concatenated outputs of the exchanges */
+func agree(exchanges []exchange, info []byte) ([]byte, error) {
+	var km []byte
+	for _, e := range exchanges {
+		dh, err := e.sk.SharedSecretChecked(e.pk)
+		if err != nil {
+			return nil, err
+		}
+		km = append(km, dh...)
+	}
+	return kdf(km, info)
+}
+
+/* HKDF-SHA256 of F || KM, where F is 32 0xFF bytes, with a zero salt */
+func kdf(km, info []byte) ([]byte, error) {
+	ikm := make([]byte, 32, 32+len(km))
+	for i := range ikm {
+		ikm[i] = 0xFF
+	}
+	ikm = append(ikm, km...)
+	sharedKey := make([]byte, SharedKeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, ikm, make([]byte, sha256.Size), info), sharedKey); err != nil {
+		return nil, err
+	}
+	return sharedKey, nil
+}
+
+/* AD = Encode(IKA) || Encode(IKB) */
+func concatIdentities(initiator, responder *curve25519.PublicKey) []byte {
+	return append(append([]byte{}, initiator[:]...), responder[:]...)
+}
@@ -0,0 +1,115 @@
+package x3dh
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	curve25519 "github.com/moonfruit/go-curve25519"
+	"github.com/stretchr/testify/require"
+)
+
+var reader = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+var info = []byte("x3dh test")
+
+func decodeHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
+
+func TestVectors(t *testing.T) {
+	// random keys, with the shared keys recomputed by testdata/x3dh.py, a
+	// separate Python implementation of the X3DH specification; the
+	// signatures are Sign's own
+	file, err := os.Open(filepath.Join("testdata", "x3dh.txt"))
+	require.NoError(t, err)
+	defer file.Close()
+
+	tested := 0
+	for {
+		var hexAlice, hexBob, hexSignedPreKey, hexOneTimePreKey, hexEphemeral, hexSignature, hexSharedKey string
+		n, err := fmt.Fscanln(file, &hexAlice, &hexBob, &hexSignedPreKey, &hexOneTimePreKey, &hexEphemeral, &hexSignature, &hexSharedKey)
+		if n == 0 && err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		alice := curve25519.NewPrivateKey(decodeHex(t, hexAlice))
+		bob := curve25519.NewPrivateKey(decodeHex(t, hexBob))
+		signedPreKey := NewSignedPreKey(bob, 1, curve25519.NewPrivateKey(decodeHex(t, hexSignedPreKey)))
+		require.Equal(t, hexSignature, signedPreKey.Signature.String())
+		var oneTimePreKey *OneTimePreKey
+		if hexOneTimePreKey != "-" {
+			oneTimePreKey = &OneTimePreKey{2, curve25519.NewPrivateKey(decodeHex(t, hexOneTimePreKey))}
+		}
+
+		bundle := NewBundle(bob, signedPreKey, oneTimePreKey)
+		sharedKey, associatedData, message, err := Initiate(bytes.NewReader(decodeHex(t, hexEphemeral)), alice, bundle, info)
+		require.NoError(t, err)
+		require.Equal(t, hexSharedKey, hex.EncodeToString(sharedKey))
+		require.Equal(t, append(alice.Public()[:], bob.Public()[:]...), associatedData)
+		require.Equal(t, oneTimePreKey != nil, message.HasOneTimePreKey)
+
+		responderKey, responderData, err := Respond(bob, signedPreKey, oneTimePreKey, message, info)
+		require.NoError(t, err)
+		require.Equal(t, sharedKey, responderKey)
+		require.Equal(t, associatedData, responderData)
+		tested++
+	}
+	require.Equal(t, 16, tested)
+}
+
+func TestX3DH(t *testing.T) {
+	alice := curve25519.GenerateKeyFrom(reader)
+	bob := curve25519.GenerateKeyFrom(reader)
+	signedPreKey := NewSignedPreKey(bob, 7, curve25519.GenerateKeyFrom(reader))
+	oneTimePreKey := &OneTimePreKey{42, curve25519.GenerateKeyFrom(reader)}
+	bundle := NewBundle(bob, signedPreKey, oneTimePreKey)
+	require.NoError(t, bundle.Verify())
+
+	sharedKey, associatedData, message, err := Initiate(reader, alice, bundle, info)
+	require.NoError(t, err)
+	require.Len(t, sharedKey, SharedKeySize)
+	require.Equal(t, uint32(7), message.SignedPreKeyID)
+	require.Equal(t, uint32(42), message.OneTimePreKeyID)
+
+	responderKey, responderData, err := Respond(bob, signedPreKey, oneTimePreKey, message, info)
+	require.NoError(t, err)
+	require.Equal(t, sharedKey, responderKey)
+	require.Equal(t, associatedData, responderData)
+
+	// a different info gives a different key
+	responderKey, _, err = Respond(bob, signedPreKey, oneTimePreKey, message, []byte("other"))
+	require.NoError(t, err)
+	require.NotEqual(t, sharedKey, responderKey)
+
+	// the wrong prekeys
+	_, _, err = Respond(bob, signedPreKey, nil, message, info)
+	require.Error(t, err)
+	_, _, err = Respond(bob, NewSignedPreKey(bob, 8, signedPreKey.Key), oneTimePreKey, message, info)
+	require.Error(t, err)
+	_, _, err = Respond(bob, signedPreKey, &OneTimePreKey{43, oneTimePreKey.Key}, message, info)
+	require.Error(t, err)
+
+	// a forged signed prekey
+	forged := *bundle
+	forged.SignedPreKey = *curve25519.GenerateKeyFrom(reader).Public()
+	_, _, _, err = Initiate(reader, alice, &forged, info)
+	require.Equal(t, ErrInvalidSignature, err)
+	forged = *bundle
+	forged.IdentityKey = *curve25519.GenerateKeyFrom(reader).Public()
+	require.Equal(t, ErrInvalidSignature, forged.Verify())
+
+	// a low-order ephemeral key
+	message.EphemeralKey = curve25519.PublicKey{}
+	_, _, err = Respond(bob, signedPreKey, oneTimePreKey, message, info)
+	require.Equal(t, curve25519.ErrLowOrderPoint, err)
+}
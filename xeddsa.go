@@ -0,0 +1,137 @@
+package curve25519
+
+import (
+	"crypto/sha512"
+	"crypto/subtle"
+)
+
+/* XEdDSA, as specified by Signal (https://signal.org/docs/specifications/xeddsa/)
+ *
+ * signing:
+ *
+ *    A, a = calculate_key_pair(k)
+ *    r = hash1(a || M || Z) (mod q)
+ *    R = rB
+ *    h = hash(R || A || M) (mod q)
+ *    s = r + ha (mod q)
+ *
+ *    output (R,s) as the signature
+ *
+ * verification:
+ *
+ *    A = convert_mont(u)
+ *    h = hash(R || A || M) (mod q)
+ *    confirm  R == sB - hA
+ *
+ * libsignal doesn't negate a when calculating the key pair, but stores the
+ * sign bit of A in the otherwise unused top bit of s instead.  We sign as the
+ * specification says, whose signatures always have that bit clear, and take
+ * the sign of A from it when verifying, so signatures from either side are
+ * accepted.
+ */
+
+/* hash_i(X) = hash(2^256 - 1 - i || X) */
+func hashI(i byte, parts ...[]byte) []byte {
+	prefix := make([]byte, 32)
+	for j := range prefix {
+		prefix[j] = 0xFF
+	}
+	prefix[0] -= i
+
+	hash := sha512.New()
+	hash.Write(prefix)
+	for _, part := range parts {
+		hash.Write(part)
+	}
+	return hash.Sum(nil)
+}
+
+/* Convert a Montgomery private key to an Edwards key pair whose public key
+ * has its sign bit clear
+ *   A  [out] Edwards public key
+ *   a  [out] Edwards private key
+ *   k  [in]  Montgomery private key
+ */
+func calculateKeyPair(k []byte) (A []byte, a *Scalar) {
+	a = new(Scalar).Reduce(NewScalar(k))
+	A = new(edPoint).scalarBaseMult(a[:]).bytes()
+
+	negA := new(Scalar).Negate(a)
+	subtle.ConstantTimeCopy(int(A[31]>>7), a[:], negA[:])
+	A[31] &= 0x7F
+	return
+}
+
+/* Convert a Montgomery u-coordinate to the Edwards point with the given sign
+ *   y = (u - 1) / (u + 1)
+ * WARNING: this function has data-dependent timing */
+func convertMont(u []byte, sign int) (*edPoint, bool) {
+	if !isCanonicalPublicKey(u) {
+		return nil, false
+	}
+	one := new(FieldElement).One()
+	mu, _ := new(FieldElement).SetBytes(u)
+	y := new(FieldElement).Add(mu, one)
+	y.Invert(y)
+	y.Mul(y, new(FieldElement).Sub(mu, one))
+	return new(edPoint).setY(y, sign)
+}
+
+/* XEdDSA signature generation
+ *   sig  [out] 64-byte signature
+ *   k    [in]  Montgomery private key
+ *   M    [in]  message
+ *   Z    [in]  64 bytes of secure random data
+ */
+func xeddsaSign(sig, k, M, Z []byte) {
+	A, a := calculateKeyPair(k)
+	r := NewScalarWide(hashI(1, a[:], M, Z))
+	R := new(edPoint).scalarBaseMult(r[:]).bytes()
+
+	hash := sha512.New()
+	hash.Write(R)
+	hash.Write(A)
+	hash.Write(M)
+	h := NewScalarWide(hash.Sum(nil))
+
+	s := new(Scalar).Mul(h, a)
+	s.Add(s, r)
+
+	copy(sig, R)
+	copy(sig[32:], s[:])
+}
+
+/* XEdDSA signature verification
+ *   u    [in]  Montgomery public key
+ *   M    [in]  message
+ *   sig  [in]  64-byte signature
+ */
+func xeddsaVerify(u, M, sig []byte) bool {
+	if len(sig) != 64 {
+		return false
+	}
+	R := sig[:32]
+	s := append(sig[:0:0], sig[32:]...)
+	sign := int(s[31] >> 7)
+	s[31] &= 0x7F
+	if (s[31] & 0xE0) != 0 {
+		return false
+	}
+
+	A, ok := convertMont(u, sign)
+	if !ok {
+		return false
+	}
+
+	hash := sha512.New()
+	hash.Write(R)
+	hash.Write(A.bytes())
+	hash.Write(M)
+	h := NewScalarWide(hash.Sum(nil))
+
+	/* Rcheck = sB - hA */
+	check := new(edPoint).scalarMult(h[:], A)
+	check.negate(check)
+	check.add(check, new(edPoint).scalarBaseMult(s))
+	return subtle.ConstantTimeCompare(check.bytes(), R) == 1
+}
@@ -0,0 +1,101 @@
+package curve25519
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEdwards(t *testing.T) {
+	require.True(t, new(edPoint).scalarBaseMult(order).isIdentity())
+	require.False(t, new(edPoint).scalarBaseMult(NewScalar([]byte{1})[:]).isIdentity())
+
+	for i := 0; i < 100; i++ {
+		a := NewScalar(randomBytes(32))
+		b := NewScalar(randomBytes(32))
+		aB := new(edPoint).scalarBaseMult(new(Scalar).Reduce(a)[:])
+		bB := new(edPoint).scalarBaseMult(new(Scalar).Reduce(b)[:])
+
+		sum := new(edPoint).add(aB, bB)
+		require.Equal(t, new(edPoint).scalarBaseMult(new(Scalar).Add(a, b)[:]).bytes(), sum.bytes())
+		require.Equal(t, new(edPoint).add(aB, aB).bytes(), new(edPoint).double(aB).bytes())
+
+		decoded, ok := new(edPoint).setBytes(sum.bytes())
+		require.True(t, ok)
+		require.True(t, decoded.equal(sum))
+	}
+}
+
+func TestXEdDSAVectors(t *testing.T) {
+	// Signatures produced by libsignal, which keeps the sign bit of the
+	// Edwards public key in the top bit of s.
+	file, err := os.Open(filepath.Join("testdata", "xeddsa.txt"))
+	require.NoError(t, err)
+	defer file.Close()
+
+	for {
+		var hexPrivateKey, hexMessage, hexSignature string
+		n, err := fmt.Fscanln(file, &hexPrivateKey, &hexMessage, &hexSignature)
+		if n == 0 && err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		bytes, err := hex.DecodeString(hexPrivateKey)
+		require.NoError(t, err)
+		publicKey := NewPrivateKey(bytes).Public()
+
+		message, err := hex.DecodeString(hexMessage)
+		require.NoError(t, err)
+
+		bytes, err = hex.DecodeString(hexSignature)
+		require.NoError(t, err)
+		signature := NewSignature(bytes)
+
+		require.True(t, VerifyXEdDSA(publicKey, message, signature))
+
+		message[0] ^= 1
+		require.False(t, VerifyXEdDSA(publicKey, message, signature))
+	}
+}
+
+func TestXEdDSA(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		privateKey := GenerateKeyFrom(reader)
+		publicKey := privateKey.Public()
+		message := randomBytes(i)
+
+		A, _ := calculateKeyPair(privateKey.raw[:])
+		converted, ok := convertMont(publicKey[:], 0)
+		require.True(t, ok)
+		require.Equal(t, A, converted.bytes())
+
+		signature, err := privateKey.SignXEdDSA(reader, message)
+		require.NoError(t, err)
+		require.Zero(t, signature[63]&0x80)
+		require.True(t, VerifyXEdDSA(publicKey, message, signature))
+
+		another, err := privateKey.SignXEdDSA(reader, message)
+		require.NoError(t, err)
+		require.NotEqual(t, signature, another)
+		require.True(t, VerifyXEdDSA(publicKey, message, another))
+
+		require.False(t, VerifyXEdDSA(GenerateKeyFrom(reader).Public(), message, signature))
+		require.False(t, VerifyXEdDSA(publicKey, append(message, 0), signature))
+
+		tampered := *signature
+		tampered[63] |= 0x80
+		require.False(t, VerifyXEdDSA(publicKey, message, &tampered))
+		tampered = *signature
+		tampered[0] ^= 1
+		require.False(t, VerifyXEdDSA(publicKey, message, &tampered))
+	}
+
+	_, err := GenerateKeyFrom(reader).SignXEdDSA(io.LimitReader(reader, 63), nil)
+	require.Error(t, err)
+}